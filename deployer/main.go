@@ -1,17 +1,41 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"os"
+	"os/signal"
 	"strings"
+	"syscall"
+	"time"
 
 	"github.com/alex-roman/golang-lambda-deployer/pkg"
 	"github.com/alex-roman/golang-lambda-deployer/pkg/deployer"
+	"github.com/rs/zerolog"
 	"github.com/spf13/cobra"
 )
 
 var env string
 var tail bool
+var region string
+var profile string
+var assumeRole string
+var canarySpec string
+var alarmNames []string
+var previousAlias string
+var logLevel string
+var logFormat string
+var filterLevel string
+var packageType string
+var ecrRepository string
+var inlineArtifacts bool
+var watchPollInterval time.Duration
+var sqsQueueURL string
+var historyLimit int
+var rollbackTo string
+var rollbackAlias string
+var architectures []string
+var sourceSpec string
 
 func main() {
 	var rootCmd = &cobra.Command{
@@ -20,8 +44,49 @@ func main() {
 		Run:   runDeploy,
 	}
 
-	rootCmd.Flags().StringVarP(&env, "env", "e", "", "Environment name postfix (prod-use1|stag)")
+	rootCmd.PersistentFlags().StringVarP(&env, "env", "e", "", "Environment name postfix (prod-use1|stag)")
+	rootCmd.PersistentFlags().StringVar(&region, "region", "", "AWS region (defaults to deploy.conf REGION or us-east-1)")
+	rootCmd.PersistentFlags().StringVar(&profile, "profile", "", "AWS shared config profile to use")
+	rootCmd.PersistentFlags().StringVar(&assumeRole, "assume-role", "", "IAM role ARN to assume via STS before deploying")
+	rootCmd.PersistentFlags().StringVar(&logLevel, "log-level", "info", "Log level (debug|info|warn|error)")
+	rootCmd.PersistentFlags().StringVar(&logFormat, "log-format", "console", "Log format (json|console)")
+	rootCmd.PersistentFlags().StringVar(&packageType, "package-type", "", "Artifact backend: Zip (default) or Image")
+	rootCmd.PersistentFlags().StringVar(&ecrRepository, "ecr-repo", "", "ECR repository to push to when --package-type=Image")
+	rootCmd.PersistentFlags().BoolVar(&inlineArtifacts, "inline-artifacts", false, "Skip S3 and inline the zip directly into UpdateFunctionCode (artifacts under 50MB only)")
+
 	rootCmd.Flags().BoolVar(&tail, "tail", false, "Tail logs after deployment")
+	rootCmd.Flags().StringVar(&canarySpec, "canary", "", "Progressive traffic-shifting steps, e.g. 10:2m,50:5m,100")
+	rootCmd.Flags().StringSliceVar(&alarmNames, "alarms", nil, "CloudWatch alarm names that trigger a canary rollback")
+	rootCmd.Flags().StringVar(&previousAlias, "previous-alias", "", "Alias shifted during canary rollout (default \"stable\"); with --archs, the alias published to (default \"canary\")")
+	rootCmd.Flags().StringVar(&filterLevel, "filter-level", "", "Drop tailed log messages below this level (debug|info|warn|error)")
+	rootCmd.Flags().StringSliceVar(&architectures, "archs", nil, "Cross-compile and publish for multiple Lambda architectures, e.g. arm64,x86_64")
+	rootCmd.Flags().StringVar(&sourceSpec, "source", "", "Build from a remote ref instead of the working tree, e.g. git+ssh://git@github.com/org/repo.git#v1.2.3")
+
+	var watchCmd = &cobra.Command{
+		Use:   "watch",
+		Short: "Redeploy automatically whenever a new build artifact appears in the builds bucket",
+		Run:   runWatch,
+	}
+	watchCmd.Flags().DurationVar(&watchPollInterval, "poll-interval", 30*time.Second, "How often to poll the builds bucket for new artifacts (ignored with --sqs-queue)")
+	watchCmd.Flags().StringVar(&sqsQueueURL, "sqs-queue", "", "SQS queue URL to long-poll for s3:ObjectCreated:* events instead of polling the bucket directly")
+	rootCmd.AddCommand(watchCmd)
+
+	var historyCmd = &cobra.Command{
+		Use:   "history",
+		Short: "Print past deploys recorded in the builds bucket",
+		Run:   runHistory,
+	}
+	historyCmd.Flags().IntVar(&historyLimit, "limit", 20, "Maximum number of entries to print")
+	rootCmd.AddCommand(historyCmd)
+
+	var rollbackCmd = &cobra.Command{
+		Use:   "rollback",
+		Short: "Point an alias back at a previously deployed version or commit",
+		Run:   runRollback,
+	}
+	rollbackCmd.Flags().StringVar(&rollbackTo, "to", "", "Version or commit to roll back to (default: the previous manifest)")
+	rollbackCmd.Flags().StringVar(&rollbackAlias, "alias", "", "Alias to roll back (default \"stable\", or --previous-alias if set)")
+	rootCmd.AddCommand(rollbackCmd)
 
 	if err := rootCmd.Execute(); err != nil {
 		fmt.Println(err)
@@ -29,31 +94,239 @@ func main() {
 	}
 }
 
+// newDeployer builds a Deployer from the layered config and persistent
+// flag overrides shared by every subcommand, and initializes its AWS
+// clients.
+func newDeployer(logger *zerolog.Logger) (deployer.Deployer, error) {
+	de := deployer.Deployer{Logger: logger}
+	config, err := pkg.LoadConfig()
+	if err != nil {
+		return deployer.Deployer{}, fmt.Errorf("config.invalid: %w", err)
+	}
+	de.Config = config
+	if region != "" {
+		de.Config.Region = region
+	}
+	if profile != "" {
+		de.Config.Profile = profile
+	}
+	if assumeRole != "" {
+		de.Config.RoleARN = assumeRole
+	}
+	if packageType != "" {
+		de.Config.PackageType = packageType
+	}
+	if ecrRepository != "" {
+		de.Config.ECRRepository = ecrRepository
+	}
+	if inlineArtifacts {
+		de.Config.InlineArtifacts = true
+	}
+	if len(architectures) > 0 {
+		de.Config.Architectures = architectures
+	}
+	if sourceSpec != "" {
+		source, err := pkg.ParseSourceSpec(sourceSpec)
+		if err != nil {
+			return deployer.Deployer{}, fmt.Errorf("source.invalid_spec: %w", err)
+		}
+		de.Source = source
+	}
+
+	if err := de.InitAWSClient(); err != nil {
+		return deployer.Deployer{}, fmt.Errorf("init.failed: %w", err)
+	}
+	return de, nil
+}
+
 func runDeploy(cmd *cobra.Command, args []string) {
-	de := deployer.Deployer{}
-	de.Config = pkg.LoadConfigOrDefaults()
-	de.InitAWSClient()
+	logger := deployer.NewLogger(logLevel, logFormat)
 
-	availableFunctions := de.GetAvailableFunctions()
+	de, err := newDeployer(&logger)
+	if err != nil {
+		logger.Error().Err(err).Msg("init.failed")
+		os.Exit(1)
+	}
+
+	availableFunctions, err := de.GetAvailableFunctions()
+	if err != nil {
+		logger.Error().Err(err).Msg("list_functions.failed")
+		os.Exit(1)
+	}
+	if !contains(availableFunctions, de.Config.LambdaName) {
+		if err := de.DiscoverFunctionRegion(); err == nil {
+			availableFunctions, err = de.GetAvailableFunctions()
+			if err != nil {
+				logger.Error().Err(err).Msg("list_functions.failed")
+				os.Exit(1)
+			}
+		}
+	}
 	if !contains(availableFunctions, de.Config.LambdaName) {
 		fmt.Printf("Lambda function '%s' does not exist\n", de.Config.LambdaName)
 		fmt.Printf("Available functions are: %s\n", strings.Join(availableFunctions, ", "))
 		os.Exit(1)
 	}
 
-	availableBuckets := de.GetAvailableBuckets()
-	if !contains(availableBuckets, de.Config.BuildsBucket) {
-		fmt.Printf("S3 bucket %s does not exist\n", de.Config.BuildsBucket)
-		fmt.Printf("Available buckets are: %s\n", strings.Join(availableBuckets, ", "))
-		os.Exit(1)
+	if de.Config.PackageType != "Image" {
+		availableBuckets, err := de.GetAvailableBuckets()
+		if err != nil {
+			logger.Error().Err(err).Msg("list_buckets.failed")
+			os.Exit(1)
+		}
+		if !contains(availableBuckets, de.Config.BuildsBucket) {
+			fmt.Printf("S3 bucket %s does not exist\n", de.Config.BuildsBucket)
+			fmt.Printf("Available buckets are: %s\n", strings.Join(availableBuckets, ", "))
+			os.Exit(1)
+		}
+	}
+
+	if len(de.Config.Architectures) > 0 {
+		artifacts, err := de.BuildMulti(context.Background(), de.Config.Architectures)
+		if err != nil {
+			logger.Error().Err(err).Msg("build.failed")
+			os.Exit(1)
+		}
+
+		alias := previousAlias
+		if alias == "" {
+			alias = "canary"
+		}
+		if err := de.DeployMultiArch(context.Background(), artifacts, alias); err != nil {
+			logger.Error().Err(err).Msg("deploy.failed")
+			os.Exit(1)
+		}
+
+		if tail {
+			if err := de.TailLogs(filterLevel); err != nil {
+				logger.Error().Err(err).Msg("tail.failed")
+				os.Exit(1)
+			}
+		}
+		return
 	}
 
 	// Build and deploy the function
-	de.Build()
-	de.Deploy()
+	if err := de.Build(context.Background()); err != nil {
+		logger.Error().Err(err).Msg("build.failed")
+		os.Exit(1)
+	}
+
+	if canarySpec != "" {
+		steps, err := pkg.ParseCanarySpec(canarySpec)
+		if err != nil {
+			logger.Error().Err(err).Msg("canary.invalid_spec")
+			os.Exit(1)
+		}
+		de.Config.Canary = pkg.CanaryStrategy{
+			Steps:                steps,
+			AlarmNames:           alarmNames,
+			PreviousVersionAlias: previousAlias,
+		}
+		if err := de.DeployCanary(); err != nil {
+			logger.Error().Err(err).Msg("canary.failed")
+			os.Exit(1)
+		}
+	} else {
+		if err := de.Deploy(); err != nil {
+			logger.Error().Err(err).Msg("deploy.failed")
+			os.Exit(1)
+		}
+	}
 
 	if tail {
-		de.TailLogs()
+		if err := de.TailLogs(filterLevel); err != nil {
+			logger.Error().Err(err).Msg("tail.failed")
+			os.Exit(1)
+		}
+	}
+}
+
+func runWatch(cmd *cobra.Command, args []string) {
+	logger := deployer.NewLogger(logLevel, logFormat)
+
+	de, err := newDeployer(&logger)
+	if err != nil {
+		logger.Error().Err(err).Msg("init.failed")
+		os.Exit(1)
+	}
+
+	watcher := de.NewS3Watcher()
+	watcher.PollInterval = watchPollInterval
+	watcher.SQSQueueURL = sqsQueueURL
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	logger.Info().Str("bucket", de.Config.BuildsBucket).Msg("watch.started")
+	if err := watcher.Watch(ctx); err != nil && ctx.Err() == nil {
+		logger.Error().Err(err).Msg("watch.failed")
+		os.Exit(1)
+	}
+}
+
+func runHistory(cmd *cobra.Command, args []string) {
+	logger := deployer.NewLogger(logLevel, logFormat)
+
+	de, err := newDeployer(&logger)
+	if err != nil {
+		logger.Error().Err(err).Msg("init.failed")
+		os.Exit(1)
+	}
+
+	manifests, err := de.ListHistory(historyLimit)
+	if err != nil {
+		logger.Error().Err(err).Msg("history.failed")
+		os.Exit(1)
+	}
+
+	fmt.Printf("%-20s  %-10s  %-8s  %-6s  %-8s  %s\n", "DEPLOYED_AT", "VERSION", "ALIAS", "ARCH", "ROLLBACK", "COMMIT")
+	for _, manifest := range manifests {
+		fmt.Printf("%-20s  %-10s  %-8s  %-6s  %-8t  %s\n",
+			manifest.DeployedAt.Format(time.RFC3339),
+			manifest.Version,
+			manifest.Alias,
+			manifest.Arch,
+			manifest.Rollback,
+			manifest.Commit,
+		)
+	}
+}
+
+func runRollback(cmd *cobra.Command, args []string) {
+	logger := deployer.NewLogger(logLevel, logFormat)
+
+	de, err := newDeployer(&logger)
+	if err != nil {
+		logger.Error().Err(err).Msg("init.failed")
+		os.Exit(1)
+	}
+
+	alias := rollbackAlias
+	if alias == "" {
+		alias = previousAlias
+	}
+	if alias == "" {
+		alias = "stable"
+	}
+
+	target := rollbackTo
+	if target == "" {
+		manifests, err := de.ListHistory(2)
+		if err != nil {
+			logger.Error().Err(err).Msg("history.failed")
+			os.Exit(1)
+		}
+		if len(manifests) < 2 {
+			logger.Error().Msg("rollback.no_previous_deploy")
+			os.Exit(1)
+		}
+		target = manifests[1].Version
+	}
+
+	if err := de.Rollback(target, alias); err != nil {
+		logger.Error().Err(err).Msg("rollback.failed")
+		os.Exit(1)
 	}
 }
 
@@ -0,0 +1,91 @@
+package blob
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// FileStorage backs Storage with a local directory, for CI caches and
+// air-gapped deployments that have no object store at all.
+type FileStorage struct {
+	root string
+}
+
+func NewFileStorage(root string) *FileStorage {
+	return &FileStorage{root: root}
+}
+
+func (f *FileStorage) path(key string) string {
+	return filepath.Join(f.root, key)
+}
+
+func (f *FileStorage) Put(ctx context.Context, key string, r io.Reader) error {
+	path := f.path(key)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("error creating directory for %s: %w", path, err)
+	}
+
+	out, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("error creating %s: %w", path, err)
+	}
+	defer out.Close()
+
+	if _, err := io.Copy(out, r); err != nil {
+		return fmt.Errorf("error writing %s: %w", path, err)
+	}
+	return nil
+}
+
+func (f *FileStorage) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	file, err := os.Open(f.path(key))
+	if err != nil {
+		return nil, fmt.Errorf("error reading %s: %w", f.path(key), err)
+	}
+	return file, nil
+}
+
+func (f *FileStorage) Exists(ctx context.Context, key string) (bool, error) {
+	_, err := os.Stat(f.path(key))
+	if os.IsNotExist(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("error checking %s: %w", f.path(key), err)
+	}
+	return true, nil
+}
+
+func (f *FileStorage) List(ctx context.Context, prefix string) ([]string, error) {
+	var keys []string
+
+	err := filepath.Walk(f.root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		if info.IsDir() {
+			return nil
+		}
+
+		rel, err := filepath.Rel(f.root, path)
+		if err != nil {
+			return err
+		}
+		if strings.HasPrefix(rel, prefix) {
+			keys = append(keys, rel)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error listing %s/%s*: %w", f.root, prefix, err)
+	}
+
+	return keys, nil
+}
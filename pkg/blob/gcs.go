@@ -0,0 +1,75 @@
+package blob
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+
+	"cloud.google.com/go/storage"
+	"google.golang.org/api/iterator"
+)
+
+// GCSStorage backs Storage with a Google Cloud Storage bucket, for teams
+// running their build cache outside AWS.
+type GCSStorage struct {
+	client *storage.Client
+	bucket string
+}
+
+func NewGCSStorage(ctx context.Context, bucket string) (*GCSStorage, error) {
+	client, err := storage.NewClient(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error creating GCS client: %w", err)
+	}
+	return &GCSStorage{client: client, bucket: bucket}, nil
+}
+
+func (g *GCSStorage) Put(ctx context.Context, key string, r io.Reader) error {
+	w := g.client.Bucket(g.bucket).Object(key).NewWriter(ctx)
+	if _, err := io.Copy(w, r); err != nil {
+		w.Close()
+		return fmt.Errorf("error uploading %s to gs://%s: %w", key, g.bucket, err)
+	}
+	if err := w.Close(); err != nil {
+		return fmt.Errorf("error finalizing gs://%s/%s: %w", g.bucket, key, err)
+	}
+	return nil
+}
+
+func (g *GCSStorage) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	r, err := g.client.Bucket(g.bucket).Object(key).NewReader(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error reading gs://%s/%s: %w", g.bucket, key, err)
+	}
+	return r, nil
+}
+
+func (g *GCSStorage) Exists(ctx context.Context, key string) (bool, error) {
+	_, err := g.client.Bucket(g.bucket).Object(key).Attrs(ctx)
+	if errors.Is(err, storage.ErrObjectNotExist) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("error checking gs://%s/%s: %w", g.bucket, key, err)
+	}
+	return true, nil
+}
+
+func (g *GCSStorage) List(ctx context.Context, prefix string) ([]string, error) {
+	var keys []string
+
+	it := g.client.Bucket(g.bucket).Objects(ctx, &storage.Query{Prefix: prefix})
+	for {
+		attrs, err := it.Next()
+		if errors.Is(err, iterator.Done) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("error listing gs://%s/%s*: %w", g.bucket, prefix, err)
+		}
+		keys = append(keys, attrs.Name)
+	}
+
+	return keys, nil
+}
@@ -0,0 +1,49 @@
+// Package blob abstracts over the object store build artifacts live in, so
+// Deployer can be pointed at S3, GCS, or a local directory by changing a
+// single BuildsBucket URL rather than its code.
+package blob
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/url"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// Storage is a minimal key/blob store: enough to publish a build artifact
+// and look up prior ones by key, without committing callers to any one
+// backend's SDK.
+type Storage interface {
+	Put(ctx context.Context, key string, r io.Reader) error
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	Exists(ctx context.Context, key string) (bool, error)
+	List(ctx context.Context, prefix string) ([]string, error)
+}
+
+// New selects a Storage implementation from uri's scheme: "s3://bucket"
+// (reusing s3Client), "gs://bucket", or "file:///path". A bare bucket name
+// with no scheme, such as Config.BuildsBucket's historical default, is
+// treated as "s3://<name>" for backward compatibility.
+func New(ctx context.Context, uri string, s3Client *s3.Client) (Storage, error) {
+	u, err := url.Parse(uri)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing storage URL %q: %w", uri, err)
+	}
+
+	switch u.Scheme {
+	case "", "s3":
+		bucket := u.Host
+		if bucket == "" {
+			bucket = uri
+		}
+		return NewS3Storage(s3Client, bucket), nil
+	case "gs":
+		return NewGCSStorage(ctx, u.Host)
+	case "file":
+		return NewFileStorage(u.Path), nil
+	default:
+		return nil, fmt.Errorf("unsupported storage scheme %q", u.Scheme)
+	}
+}
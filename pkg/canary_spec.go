@@ -0,0 +1,44 @@
+package pkg
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ParseCanarySpec parses a --canary flag value of the form
+// "10:2m,50:5m,100" into a slice of CanaryStep. The hold duration may be
+// omitted on any step (most commonly the final 100% step), in which case
+// it defaults to zero.
+func ParseCanarySpec(spec string) ([]CanaryStep, error) {
+	if spec == "" {
+		return nil, nil
+	}
+
+	var steps []CanaryStep
+	for _, raw := range strings.Split(spec, ",") {
+		raw = strings.TrimSpace(raw)
+		if raw == "" {
+			continue
+		}
+
+		parts := strings.SplitN(raw, ":", 2)
+		percent, err := strconv.Atoi(strings.TrimSpace(parts[0]))
+		if err != nil {
+			return nil, fmt.Errorf("invalid canary step %q: %w", raw, err)
+		}
+
+		var hold time.Duration
+		if len(parts) == 2 && strings.TrimSpace(parts[1]) != "" {
+			hold, err = time.ParseDuration(strings.TrimSpace(parts[1]))
+			if err != nil {
+				return nil, fmt.Errorf("invalid canary hold in step %q: %w", raw, err)
+			}
+		}
+
+		steps = append(steps, CanaryStep{Percent: percent, Hold: hold})
+	}
+
+	return steps, nil
+}
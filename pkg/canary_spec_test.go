@@ -0,0 +1,53 @@
+package pkg
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseCanarySpec(t *testing.T) {
+	tests := []struct {
+		name    string
+		spec    string
+		want    []CanaryStep
+		wantErr bool
+	}{
+		{
+			name: "empty",
+			spec: "",
+			want: nil,
+		},
+		{
+			name: "steps with final no-hold",
+			spec: "10:2m,50:5m,100",
+			want: []CanaryStep{
+				{Percent: 10, Hold: 2 * time.Minute},
+				{Percent: 50, Hold: 5 * time.Minute},
+				{Percent: 100, Hold: 0},
+			},
+		},
+		{
+			name:    "invalid percent",
+			spec:    "abc:2m",
+			wantErr: true,
+		},
+		{
+			name:    "invalid duration",
+			spec:    "10:notaduration",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseCanarySpec(tt.spec)
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.want, got)
+		})
+	}
+}
@@ -1,13 +1,21 @@
 package pkg
 
 import (
+	"bytes"
+	"encoding/json"
+	"errors"
 	"fmt"
-	"io"
+	"io/fs"
+	"net/url"
 	"os"
 	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
 
 	"github.com/hashicorp/go-envparse"
 	"github.com/mitchellh/mapstructure"
+	"gopkg.in/yaml.v3"
 )
 
 type DeployConfig struct {
@@ -17,66 +25,316 @@ type DeployConfig struct {
 	SourceCodeFilename string // LambdaName + commit + '.zip'; it appends -dirty if there are uncommitted changes
 	BuildsBucket       string `json:"builds_bucket" env:"BUILDS_BUCKET"  mapstructure:"BUILDS_BUCKET"`
 	LogGroupName       string `json:"log_group_name" env:"LOG_GROUP_NAME"  mapstructure:"LOG_GROUP_NAME"`
+
+	// Region is the AWS region the Lambda function is deployed in. Falls
+	// back to "us-east-1" when unset.
+	Region string `json:"region" env:"REGION"  mapstructure:"REGION"`
+	// Profile is the named profile to source credentials from in the
+	// shared AWS config/credentials files.
+	Profile string `json:"profile" env:"PROFILE"  mapstructure:"PROFILE"`
+	// RoleARN, when set, is assumed via STS on top of the profile/default
+	// credential chain before any AWS calls are made.
+	RoleARN string `json:"role_arn" env:"ROLE_ARN"  mapstructure:"ROLE_ARN"`
+	// RegionCandidates is the list of regions scanned for the function
+	// when it cannot be found in Region. Defaults to a small set of
+	// common regions when empty.
+	RegionCandidates []string `json:"region_candidates" env:"REGION_CANDIDATES"  mapstructure:"REGION_CANDIDATES"`
+
+	// Canary configures progressive traffic shifting for Deployer.DeployCanary.
+	// It is populated from the CLI (--canary, --alarms, --previous-alias)
+	// rather than deploy.conf, since it describes a single deploy's rollout
+	// rather than persistent environment configuration.
+	Canary CanaryStrategy `json:"-"`
+
+	// PackageType selects the ArtifactSource used to package and publish
+	// the build, matching Lambda's own PackageType field: "Zip" (default)
+	// or "Image".
+	PackageType string `json:"package_type" env:"PACKAGE_TYPE"  mapstructure:"PACKAGE_TYPE"`
+	// ECRRepository is the ECR repository container images are pushed to
+	// when PackageType is "Image".
+	ECRRepository string `json:"ecr_repository" env:"ECR_REPOSITORY"  mapstructure:"ECR_REPOSITORY"`
+	// InlineArtifacts, when PackageType is "Zip", skips S3 and hands the
+	// zip bytes directly to UpdateFunctionCode. A zip over Lambda's 50MB
+	// inline limit fails packaging; it is not uploaded to S3 instead.
+	InlineArtifacts bool `json:"inline_artifacts" env:"INLINE_ARTIFACTS"  mapstructure:"INLINE_ARTIFACTS"`
+
+	// BuildSHA256 is the SHA256 of SourceCodeFilename's zip contents,
+	// computed by Deployer.Build. DeployFromLocation and DeployCanary
+	// record it as the published version's description, so two builds of
+	// the same commit can be verified to have produced the same artifact.
+	BuildSHA256 string `json:"-"`
+
+	// Architectures, when set, switches Deployer.BuildMulti on and lists
+	// the Lambda architectures ("arm64", "x86_64") to cross-compile and
+	// publish in a single invocation, instead of the single arch
+	// Deployer.Build infers from the live function's configuration.
+	Architectures []string `json:"architectures" env:"ARCHITECTURES"  mapstructure:"ARCHITECTURES"`
+}
+
+// CanaryStep shifts Percent of traffic to the new version and holds for
+// Hold before evaluating alarms and moving on to the next step.
+type CanaryStep struct {
+	Percent int
+	Hold    time.Duration
 }
 
-func LoadConfig() (DeployConfig, error) {
-	var config DeployConfig
+// CanaryStrategy describes a progressive traffic-shifting rollout,
+// modeled after CodeDeploy's Lambda deployment preferences.
+type CanaryStrategy struct {
+	Steps []CanaryStep
+	// AlarmNames are polled after each step; if any alarm is in ALARM
+	// state the rollout is aborted and PreviousVersionAlias is reset to
+	// the version it pointed to before the rollout started.
+	AlarmNames []string
+	// PreviousVersionAlias is the alias shifted across versions during
+	// the rollout and promoted to the new version on success. Defaults
+	// to "stable".
+	PreviousVersionAlias string
+}
+
+// configCandidates lists the config files LoadConfig auto-detects, in the
+// order they're tried; the first one present wins and the rest are
+// ignored. Structured formats are preferred over deploy.conf so a repo
+// migrating to deploy.yaml/deploy.json doesn't have to delete the old
+// file in the same change.
+var configCandidates = []string{"deploy.json", "deploy.yaml", "deploy.yml", "deploy.conf"}
 
-	configFile := "deploy.conf"
-	if _, err := os.Stat(configFile); os.IsNotExist(err) {
-		return DeployConfig{}, fmt.Errorf("config file %s does not exist", configFile)
+// envPrefix marks the process environment variables LoadConfig treats as
+// config overrides, so "DEPLOYER_BUILDS_BUCKET" overrides BuildsBucket
+// without LoadConfig having to care about unrelated environment noise.
+const envPrefix = "DEPLOYER_"
+
+var (
+	// bucketNameRe is a simplified version of S3's bucket naming rules:
+	// lowercase letters, digits, dots and hyphens, 3-63 characters,
+	// starting and ending with a letter or digit.
+	bucketNameRe = regexp.MustCompile(`^[a-z0-9][a-z0-9.-]{1,61}[a-z0-9]$`)
+	// logGroupNameRe matches CloudWatch Logs' allowed log group characters.
+	logGroupNameRe = regexp.MustCompile(`^[.\-_/#A-Za-z0-9]+$`)
+)
+
+// validArchitectures are lambda/types.Architecture's valid values.
+// Deployer.compileArch translates these into the GOARCH it cross-compiles
+// for.
+var validArchitectures = map[string]bool{"x86_64": true, "arm64": true}
+
+// loadOptions holds the filesystem and environment LoadConfig reads from.
+// Both default to the real process, and are only overridden by tests.
+type loadOptions struct {
+	fsys    fs.FS
+	environ []string
+}
+
+// Option customizes LoadConfig. It exists so tests can inject an
+// in-memory filesystem or a fixed environment instead of reaching into
+// the real one.
+type Option func(*loadOptions)
+
+// WithFS overrides the filesystem LoadConfig auto-detects
+// deploy.conf/deploy.yaml/deploy.json from. Defaults to the process's
+// working directory.
+func WithFS(fsys fs.FS) Option {
+	return func(o *loadOptions) { o.fsys = fsys }
+}
+
+// WithEnviron overrides the "KEY=VALUE" entries LoadConfig scans for
+// DEPLOYER_-prefixed overrides. Defaults to os.Environ().
+func WithEnviron(environ []string) Option {
+	return func(o *loadOptions) { o.environ = environ }
+}
+
+// LoadConfig builds a DeployConfig by layering, in increasing precedence:
+// built-in defaults, an auto-detected deploy.conf, deploy.yaml or
+// deploy.json, and DEPLOYER_-prefixed environment variables.
+// Command-line flags are layered on top by callers (see
+// deployer/main.go's newDeployer), since they're specific to each
+// subcommand rather than part of this shared config. The result is
+// validated before it's returned; a malformed field anywhere in the
+// stack produces an aggregated error rather than a silent default or a
+// process exit.
+func LoadConfig(opts ...Option) (DeployConfig, error) {
+	options := loadOptions{fsys: os.DirFS("."), environ: os.Environ()}
+	for _, opt := range opts {
+		opt(&options)
 	}
 
-	fileReader, err := openConfigFile(configFile)
+	config := defaultConfig()
+
+	fileValues, err := readConfigFile(options.fsys)
 	if err != nil {
-		return DeployConfig{}, fmt.Errorf("error opening config file %s: %w", configFile, err)
+		return DeployConfig{}, err
+	}
+	if err := decodeInto(&config, fileValues); err != nil {
+		return DeployConfig{}, fmt.Errorf("error decoding config file: %w", err)
 	}
 
-	configHashmap, err := envparse.Parse(fileReader)
-	if err != nil {
-		return DeployConfig{}, fmt.Errorf("error parsing config file %s: %w", configFile, err)
+	if err := decodeInto(&config, readEnviron(options.environ)); err != nil {
+		return DeployConfig{}, fmt.Errorf("error decoding environment variables: %w", err)
 	}
 
-	err = mapstructure.Decode(configHashmap, &config)
-	if err != nil {
-		return DeployConfig{}, fmt.Errorf("error decoding config hashmap: %w", err)
+	config.finalize()
+
+	if err := config.Validate(); err != nil {
+		return DeployConfig{}, err
 	}
 
 	return config, nil
 }
 
-func LoadConfigOrDefaults() DeployConfig {
-	config, _ := LoadConfig()
+// Validate checks the fields Deployer relies on being well-formed,
+// aggregating every violation it finds instead of stopping at the first.
+func (c DeployConfig) Validate() error {
+	var errs []error
 
-	if config.Env == "" {
-		config.Env = "stag"
+	if c.AppName == "" {
+		errs = append(errs, fmt.Errorf("app_name must not be empty"))
 	}
-	if config.AppName == "" {
-		config.AppName = getAppName()
+	if !validBuildsBucket(c.BuildsBucket) {
+		errs = append(errs, fmt.Errorf("builds_bucket %q is not a valid bucket (a bare S3 bucket name, or an s3://, gs://, or file:// URL)", c.BuildsBucket))
 	}
-	if config.BuildsBucket == "" {
-		config.BuildsBucket = "e4f-builds"
+	if !logGroupNameRe.MatchString(c.LogGroupName) {
+		errs = append(errs, fmt.Errorf("log_group_name %q is not a valid CloudWatch log group name", c.LogGroupName))
 	}
-	config.LambdaName = fmt.Sprintf("%s-%s", config.AppName, config.Env)
-	if config.LogGroupName == "" {
-		config.LogGroupName = fmt.Sprintf("/aws/lambda/%s-%s", config.AppName, config.Env)
+	for _, arch := range c.Architectures {
+		if !validArchitectures[arch] {
+			errs = append(errs, fmt.Errorf("architectures: %q must be one of x86_64, arm64", arch))
+		}
 	}
-	return config
+
+	return errors.Join(errs...)
 }
 
-func getAppName() string {
-	dir, err := os.Getwd()
+// validBuildsBucket reports whether uri is one of the forms blob.New
+// accepts: a bare S3 bucket name, or an "s3://", "gs://", or "file://"
+// URL.
+func validBuildsBucket(uri string) bool {
+	u, err := url.Parse(uri)
 	if err != nil {
-		fmt.Println("Error getting current directory:", err)
-		os.Exit(1)
+		return false
+	}
+
+	switch u.Scheme {
+	case "":
+		return bucketNameRe.MatchString(uri)
+	case "s3":
+		return bucketNameRe.MatchString(u.Host)
+	case "gs":
+		return u.Host != ""
+	case "file":
+		return u.Path != ""
+	default:
+		return false
+	}
+}
+
+// defaultConfig returns the built-in defaults LoadConfig starts from,
+// before the config file and environment layers are applied.
+func defaultConfig() DeployConfig {
+	return DeployConfig{
+		Env:          "stag",
+		AppName:      getAppName(),
+		BuildsBucket: "e4f-builds",
+		Region:       "us-east-1",
 	}
-	return filepath.Base(dir)
 }
 
-func openConfigFile(configFile string) (io.Reader, error) {
-	f, err := os.Open(configFile)
+// finalize derives the fields computed from the rest of the config, once
+// every layer has been applied.
+func (c *DeployConfig) finalize() {
+	c.LambdaName = fmt.Sprintf("%s-%s", c.AppName, c.Env)
+	if c.LogGroupName == "" {
+		c.LogGroupName = fmt.Sprintf("/aws/lambda/%s-%s", c.AppName, c.Env)
+	}
+}
+
+// readConfigFile reads the first of configCandidates present in fsys and
+// decodes it into a generic map, keyed by the same names as each field's
+// mapstructure tag. A missing config file is not an error: it simply
+// leaves the defaults layer untouched.
+func readConfigFile(fsys fs.FS) (map[string]interface{}, error) {
+	for _, name := range configCandidates {
+		data, err := fs.ReadFile(fsys, name)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("error reading config file %s: %w", name, err)
+		}
+
+		switch filepath.Ext(name) {
+		case ".yaml", ".yml":
+			var values map[string]interface{}
+			if err := yaml.Unmarshal(data, &values); err != nil {
+				return nil, fmt.Errorf("error parsing config file %s: %w", name, err)
+			}
+			return values, nil
+		case ".json":
+			var values map[string]interface{}
+			if err := json.Unmarshal(data, &values); err != nil {
+				return nil, fmt.Errorf("error parsing config file %s: %w", name, err)
+			}
+			return values, nil
+		default:
+			raw, err := envparse.Parse(bytes.NewReader(data))
+			if err != nil {
+				return nil, fmt.Errorf("error parsing config file %s: %w", name, err)
+			}
+			values := make(map[string]interface{}, len(raw))
+			for k, v := range raw {
+				values[k] = v
+			}
+			return values, nil
+		}
+	}
+
+	return nil, nil
+}
+
+// readEnviron extracts DEPLOYER_-prefixed entries from environ, keyed by
+// the same names as each field's mapstructure tag, e.g.
+// "DEPLOYER_BUILDS_BUCKET" becomes values["BUILDS_BUCKET"].
+func readEnviron(environ []string) map[string]interface{} {
+	values := make(map[string]interface{})
+	for _, kv := range environ {
+		key, value, found := strings.Cut(kv, "=")
+		if !found || !strings.HasPrefix(key, envPrefix) {
+			continue
+		}
+		values[strings.TrimPrefix(key, envPrefix)] = value
+	}
+	return values
+}
+
+// decodeInto merges values onto config, converting between types (e.g. a
+// comma-separated string into a []string) the way envparse/env-var-backed
+// layers need. WeaklyTypedInput alone would lift a whole string like
+// "arm64,x86_64" into a single-element slice, so a StringToSliceHookFunc
+// is registered to split it first.
+func decodeInto(config *DeployConfig, values map[string]interface{}) error {
+	if len(values) == 0 {
+		return nil
+	}
+
+	decoder, err := mapstructure.NewDecoder(&mapstructure.DecoderConfig{
+		WeaklyTypedInput: true,
+		DecodeHook:       mapstructure.StringToSliceHookFunc(","),
+		Result:           config,
+	})
 	if err != nil {
-		return nil, fmt.Errorf("error opening config file %s: %w", configFile, err)
+		return err
 	}
-	return f, nil
+	return decoder.Decode(values)
+}
+
+// getAppName derives the default AppName from the working directory's
+// base name. An error here (e.g. the cwd was removed out from under the
+// process) is swallowed in favor of an empty default: Validate rejects an
+// empty AppName with a clear message instead of the process exiting here
+// before there's even a Logger to report it through.
+func getAppName() string {
+	dir, err := os.Getwd()
+	if err != nil {
+		return ""
+	}
+	return filepath.Base(dir)
 }
@@ -1,8 +1,8 @@
 package pkg
 
 import (
-	"os"
 	"testing"
+	"testing/fstest"
 
 	"github.com/stretchr/testify/require"
 )
@@ -10,38 +10,144 @@ import (
 func TestLoadConfig(t *testing.T) {
 	tests := []struct {
 		name    string
-		payload string
+		fsys    fstest.MapFS
+		environ []string
 		want    DeployConfig
 		wantErr bool
 	}{
 		{
-			name:    "test",
-			payload: "ENV=test\nAPP_NAME=test\nBUILDS_BUCKET=test\nLOG_GROUP_NAME=test",
+			name: "deploy.conf with defaults filled in",
+			fsys: fstest.MapFS{
+				"deploy.conf": {Data: []byte("APP_NAME=widget\nBUILDS_BUCKET=widget-builds")},
+			},
+			want: DeployConfig{
+				Env:          "stag",
+				AppName:      "widget",
+				LambdaName:   "widget-stag",
+				BuildsBucket: "widget-builds",
+				LogGroupName: "/aws/lambda/widget-stag",
+				Region:       "us-east-1",
+			},
+		},
+		{
+			name: "deploy.yaml with a slice field",
+			fsys: fstest.MapFS{
+				"deploy.yaml": {Data: []byte("app_name: widget\nbuilds_bucket: widget-builds\narchitectures:\n  - arm64\n  - x86_64\n")},
+			},
+			want: DeployConfig{
+				Env:           "stag",
+				AppName:       "widget",
+				LambdaName:    "widget-stag",
+				BuildsBucket:  "widget-builds",
+				LogGroupName:  "/aws/lambda/widget-stag",
+				Region:        "us-east-1",
+				Architectures: []string{"arm64", "x86_64"},
+			},
+		},
+		{
+			name: "deploy.json takes precedence when both present",
+			fsys: fstest.MapFS{
+				"deploy.conf": {Data: []byte("APP_NAME=fromconf")},
+				"deploy.json": {Data: []byte(`{"app_name": "fromjson", "builds_bucket": "widget-builds"}`)},
+			},
+			want: DeployConfig{
+				Env:          "stag",
+				AppName:      "fromjson",
+				LambdaName:   "fromjson-stag",
+				BuildsBucket: "widget-builds",
+				LogGroupName: "/aws/lambda/fromjson-stag",
+				Region:       "us-east-1",
+			},
+		},
+		{
+			name: "deploy.conf with a comma-separated slice field",
+			fsys: fstest.MapFS{
+				"deploy.conf": {Data: []byte("APP_NAME=widget\nBUILDS_BUCKET=widget-builds\nARCHITECTURES=arm64,x86_64")},
+			},
+			want: DeployConfig{
+				Env:           "stag",
+				AppName:       "widget",
+				LambdaName:    "widget-stag",
+				BuildsBucket:  "widget-builds",
+				LogGroupName:  "/aws/lambda/widget-stag",
+				Region:        "us-east-1",
+				Architectures: []string{"arm64", "x86_64"},
+			},
+		},
+		{
+			name: "gs:// builds_bucket is accepted",
+			fsys: fstest.MapFS{
+				"deploy.conf": {Data: []byte("APP_NAME=widget\nBUILDS_BUCKET=gs://widget-builds")},
+			},
+			want: DeployConfig{
+				Env:          "stag",
+				AppName:      "widget",
+				LambdaName:   "widget-stag",
+				BuildsBucket: "gs://widget-builds",
+				LogGroupName: "/aws/lambda/widget-stag",
+				Region:       "us-east-1",
+			},
+		},
+		{
+			name: "env var overrides config file",
+			fsys: fstest.MapFS{
+				"deploy.conf": {Data: []byte("APP_NAME=fromconf\nBUILDS_BUCKET=widget-builds")},
+			},
+			environ: []string{"DEPLOYER_APP_NAME=fromenv", "IGNORED=fromenv"},
 			want: DeployConfig{
-				Env:          "test",
-				AppName:      "test",
-				BuildsBucket: "test",
-				LogGroupName: "test",
+				Env:          "stag",
+				AppName:      "fromenv",
+				LambdaName:   "fromenv-stag",
+				BuildsBucket: "widget-builds",
+				LogGroupName: "/aws/lambda/fromenv-stag",
+				Region:       "us-east-1",
 			},
 		},
 		{
-			name:    "test with defaults",
-			payload: "APP_NAME=test",
+			name: "env var with a comma-separated slice field",
+			fsys: fstest.MapFS{
+				"deploy.conf": {Data: []byte("APP_NAME=widget\nBUILDS_BUCKET=widget-builds")},
+			},
+			environ: []string{"DEPLOYER_ARCHITECTURES=arm64,x86_64"},
 			want: DeployConfig{
-				AppName: "test",
+				Env:           "stag",
+				AppName:       "widget",
+				LambdaName:    "widget-stag",
+				BuildsBucket:  "widget-builds",
+				LogGroupName:  "/aws/lambda/widget-stag",
+				Region:        "us-east-1",
+				Architectures: []string{"arm64", "x86_64"},
 			},
 		},
+		{
+			name:    "missing app name is rejected",
+			fsys:    fstest.MapFS{},
+			environ: []string{"DEPLOYER_APP_NAME="},
+			wantErr: true,
+		},
+		{
+			name: "invalid bucket name is rejected",
+			fsys: fstest.MapFS{
+				"deploy.conf": {Data: []byte("APP_NAME=widget\nBUILDS_BUCKET=Not_A_Valid_Bucket!")},
+			},
+			wantErr: true,
+		},
+		{
+			name: "invalid architecture is rejected",
+			fsys: fstest.MapFS{
+				"deploy.conf": {Data: []byte("APP_NAME=widget\nBUILDS_BUCKET=widget-builds\nARCHITECTURES=mips")},
+			},
+			wantErr: true,
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			err := os.WriteFile("deploy.conf", []byte(tt.payload), 0644)
-			require.NoError(t, err)
-			defer os.Remove("deploy.conf")
-
-			got, err := LoadConfig()
+			got, err := LoadConfig(WithFS(tt.fsys), WithEnviron(tt.environ))
 			if tt.wantErr {
 				require.Error(t, err)
+				return
 			}
+			require.NoError(t, err)
 			require.Equal(t, tt.want, got)
 		})
 	}
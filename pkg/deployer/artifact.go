@@ -0,0 +1,58 @@
+package deployer
+
+import "context"
+
+// Artifact is the packaged build output produced by ArtifactSource.Package,
+// ready to be handed to Publish.
+type Artifact struct {
+	// LocalPath is the path to the packaged zip on disk, set by the zip
+	// sources. Empty for ECRImageSource.
+	LocalPath string
+	// ImageTag is the tag applied to the built container image, set by
+	// ECRImageSource.
+	ImageTag string
+	// SizeBytes is the size of the zip at LocalPath, used by
+	// InlineZipSource to decide whether an artifact is small enough to
+	// inline.
+	SizeBytes int64
+	// Cached indicates Build found this commit's artifact already in
+	// storage and skipped rebuilding it; Publish should reuse the
+	// existing object instead of looking for a local file.
+	Cached bool
+}
+
+// LambdaCodeLocation maps onto the three ways UpdateFunctionCode can be
+// told where the new code lives: an S3 object, an ECR container image, or
+// an inline zip payload. Exactly one of (S3Bucket+S3Key), ImageUri, or
+// ZipFile is set.
+type LambdaCodeLocation struct {
+	S3Bucket string
+	S3Key    string
+	ImageUri string
+	ZipFile  []byte
+}
+
+// ArtifactSource packages a build and publishes it somewhere Lambda's
+// UpdateFunctionCode can read it from. Package and Publish are split so
+// Build can happen once per architecture while Publish can be retried
+// independently.
+type ArtifactSource interface {
+	Package(ctx context.Context) (Artifact, error)
+	Publish(ctx context.Context, artifact Artifact) (LambdaCodeLocation, error)
+}
+
+const inlineZipSizeLimit = 50 * 1024 * 1024 // Lambda's limit for ZipFile-based UpdateFunctionCode
+
+// NewArtifactSource selects an ArtifactSource based on Config.PackageType,
+// defaulting to S3ZipSource when unset.
+func (de *Deployer) NewArtifactSource() ArtifactSource {
+	switch de.Config.PackageType {
+	case "Image":
+		return &ECRImageSource{de: de}
+	default:
+		if de.Config.InlineArtifacts {
+			return &InlineZipSource{de: de}
+		}
+		return &S3ZipSource{de: de}
+	}
+}
@@ -0,0 +1,55 @@
+package deployer
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+)
+
+// ECRImageSource builds a container image with docker buildx, tags it with
+// the current git commit, and pushes it to Config.ECRRepository. Used when
+// Config.PackageType is "Image".
+type ECRImageSource struct {
+	de *Deployer
+}
+
+func (e *ECRImageSource) Package(ctx context.Context) (Artifact, error) {
+	source := e.de.source()
+	defer func() {
+		if err := source.Close(); err != nil {
+			e.de.log().Warn().Err(err).Msg("build.cleanup_failed")
+		}
+	}()
+
+	commit, dirty, err := source.Resolve(ctx)
+	if err != nil {
+		return Artifact{}, err
+	}
+	e.de.rememberResolved(commit, dirty)
+
+	tag := fmt.Sprintf("%s:%s", e.de.Config.ECRRepository, commit)
+
+	cmd := exec.CommandContext(ctx, "docker", "buildx", "build", "-t", tag, "--load", ".")
+	cmd.Dir = source.Dir()
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return Artifact{}, fmt.Errorf("error building container image: %w", err)
+	}
+
+	e.de.log().Info().Str("tag", tag).Msg("artifact.image_built")
+	return Artifact{ImageTag: tag}, nil
+}
+
+func (e *ECRImageSource) Publish(ctx context.Context, artifact Artifact) (LambdaCodeLocation, error) {
+	cmd := exec.CommandContext(ctx, "docker", "push", artifact.ImageTag)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return LambdaCodeLocation{}, fmt.Errorf("error pushing container image: %w", err)
+	}
+
+	e.de.log().Info().Str("tag", artifact.ImageTag).Msg("artifact.image_pushed")
+	return LambdaCodeLocation{ImageUri: artifact.ImageTag}, nil
+}
@@ -0,0 +1,35 @@
+package deployer
+
+import (
+	"context"
+	"fmt"
+	"os"
+)
+
+// InlineZipSource skips S3 entirely and hands the zip bytes straight to
+// UpdateFunctionCode's ZipFile field. Only valid for artifacts smaller than
+// Lambda's inline size limit (50MB).
+type InlineZipSource struct {
+	de *Deployer
+}
+
+func (i *InlineZipSource) Package(ctx context.Context) (Artifact, error) {
+	info, err := os.Stat(i.de.Config.SourceCodeFilename)
+	if err != nil {
+		return Artifact{}, fmt.Errorf("error stating built zip file: %w", err)
+	}
+	if info.Size() > inlineZipSizeLimit {
+		return Artifact{}, fmt.Errorf("artifact %s is %d bytes, over the %d byte inline limit", i.de.Config.SourceCodeFilename, info.Size(), inlineZipSizeLimit)
+	}
+	return Artifact{LocalPath: i.de.Config.SourceCodeFilename, SizeBytes: info.Size()}, nil
+}
+
+func (i *InlineZipSource) Publish(ctx context.Context, artifact Artifact) (LambdaCodeLocation, error) {
+	data, err := os.ReadFile(artifact.LocalPath)
+	if err != nil {
+		return LambdaCodeLocation{}, fmt.Errorf("error reading zip file: %w", err)
+	}
+
+	i.de.log().Info().Str("file", artifact.LocalPath).Int("size_bytes", len(data)).Msg("artifact.inlined")
+	return LambdaCodeLocation{ZipFile: data}, nil
+}
@@ -0,0 +1,50 @@
+package deployer
+
+import (
+	"context"
+	"fmt"
+	"os"
+)
+
+// S3ZipSource packages Build's output zip and uploads it to de.Storage
+// (Config.BuildsBucket). This is the deployer's original, default behavior;
+// despite the name it works against any blob.Storage backend, not just S3.
+type S3ZipSource struct {
+	de *Deployer
+}
+
+func (s *S3ZipSource) Package(ctx context.Context) (Artifact, error) {
+	info, err := os.Stat(s.de.Config.SourceCodeFilename)
+	if err != nil {
+		// Build skips rebuilding when it finds a cache hit, so there may
+		// be no local zip even though the artifact already exists in
+		// storage under this commit's key.
+		if os.IsNotExist(err) {
+			if cached, cacheErr := s.de.Storage.Exists(ctx, s.de.Config.SourceCodeFilename); cacheErr == nil && cached {
+				return Artifact{Cached: true}, nil
+			}
+		}
+		return Artifact{}, fmt.Errorf("error stating built zip file: %w", err)
+	}
+	return Artifact{LocalPath: s.de.Config.SourceCodeFilename, SizeBytes: info.Size()}, nil
+}
+
+func (s *S3ZipSource) Publish(ctx context.Context, artifact Artifact) (LambdaCodeLocation, error) {
+	if artifact.Cached {
+		s.de.log().Info().Str("key", s.de.Config.SourceCodeFilename).Msg("artifact.cache_reused")
+		return LambdaCodeLocation{S3Bucket: s.de.Config.BuildsBucket, S3Key: s.de.Config.SourceCodeFilename}, nil
+	}
+
+	zipFile, err := os.Open(artifact.LocalPath)
+	if err != nil {
+		return LambdaCodeLocation{}, fmt.Errorf("error opening zip file: %w", err)
+	}
+	defer zipFile.Close()
+
+	if err := s.de.Storage.Put(ctx, s.de.Config.SourceCodeFilename, zipFile); err != nil {
+		return LambdaCodeLocation{}, fmt.Errorf("error uploading zip artifact: %w", err)
+	}
+
+	s.de.log().Info().Str("key", s.de.Config.SourceCodeFilename).Str("bucket", s.de.Config.BuildsBucket).Msg("artifact.uploaded")
+	return LambdaCodeLocation{S3Bucket: s.de.Config.BuildsBucket, S3Key: s.de.Config.SourceCodeFilename}, nil
+}
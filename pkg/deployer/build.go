@@ -3,107 +3,233 @@ package deployer
 import (
 	"archive/zip"
 	"context"
+	"crypto/sha256"
 	"fmt"
 	"io"
 	"os"
 	"os/exec"
-	"strings"
+	"path/filepath"
+	"sort"
+	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/lambda"
+	lambdatypes "github.com/aws/aws-sdk-go-v2/service/lambda/types"
 )
 
-func (de *Deployer) Build() {
-	currentGitCommit := getCurrentGitCommit()
-	cmd := exec.Command("go", "build", "-ldflags", fmt.Sprintf("-s -w -X main.Commit=%s -X microservice.CommitHash=%s", currentGitCommit, currentGitCommit), "-o", "bootstrap", ".")
-	cmd.Env = append(os.Environ(), fmt.Sprintf("GOARCH=%s", de.determineFunctionArch()), "CGO_ENABLED=0", "GOOS=linux")
+// reproducibleFileMode is stamped on every ZIP entry instead of whatever
+// the build host's umask happens to produce, so the same commit always
+// produces a byte-identical archive.
+const reproducibleFileMode = 0755
+
+// Build cross-compiles the function for its live architecture and
+// packages it into Config.SourceCodeFilename, reusing a cached artifact
+// from Storage when Source's commit is clean and already published.
+func (de *Deployer) Build(ctx context.Context) error {
+	start := time.Now()
+
+	source := de.source()
+	defer func() {
+		if err := source.Close(); err != nil {
+			de.log().Warn().Err(err).Msg("build.cleanup_failed")
+		}
+	}()
+
+	commit, dirty, err := source.Resolve(ctx)
+	if err != nil {
+		return err
+	}
+	de.rememberResolved(commit, dirty)
+
+	arch, err := de.determineFunctionArch(ctx)
+	if err != nil {
+		return err
+	}
+
+	key := commit
+	if dirty {
+		key += "-dirty"
+	}
+	de.Config.SourceCodeFilename = fmt.Sprintf("%s-%s.zip", de.Config.LambdaName, key)
+
+	// A clean commit builds the same artifact every time, so check the
+	// build cache before spending time on a rebuild.
+	if !dirty && de.Storage != nil {
+		cached, err := de.Storage.Exists(ctx, de.Config.SourceCodeFilename)
+		if err != nil {
+			de.log().Warn().Err(err).Msg("build.cache_check_failed")
+		} else if cached {
+			sum, err := de.cachedArtifactSHA256(ctx, de.Config.SourceCodeFilename)
+			if err != nil {
+				return err
+			}
+			de.Config.BuildSHA256 = sum
+
+			de.log().Info().Str("commit", commit).Str("key", de.Config.SourceCodeFilename).Str("sha256", sum).Msg("build.cache_hit")
+			return nil
+		}
+	}
+
+	de.log().Info().Str("commit", commit).Str("arch", arch).Msg("build.start")
+
+	if err := de.compileArch(commit, arch, de.Config.SourceCodeFilename); err != nil {
+		return err
+	}
+
+	info, err := os.Stat(de.Config.SourceCodeFilename)
+	if err != nil {
+		return fmt.Errorf("error stating %s: %w", de.Config.SourceCodeFilename, err)
+	}
+
+	sum, err := fileSHA256(de.Config.SourceCodeFilename)
+	if err != nil {
+		return err
+	}
+	de.Config.BuildSHA256 = sum
+
+	de.log().Info().
+		Str("file", de.Config.SourceCodeFilename).
+		Str("sha256", sum).
+		Int64("size_bytes", info.Size()).
+		Int64("duration_ms", time.Since(start).Milliseconds()).
+		Msg("build.zip")
+	return nil
+}
+
+// compileArch cross-compiles the function for arch (a Lambda architecture,
+// "x86_64" or "arm64") at commit inside Source's checkout directory and
+// packages the resulting "bootstrap" binary into zipPath, in the current
+// working directory, as a reproducible zip.
+func (de *Deployer) compileArch(commit, arch, zipPath string) error {
+	dir := de.source().Dir()
+
+	cmd := exec.Command("go", "build", "-trimpath", "-buildvcs=false", "-ldflags", fmt.Sprintf("-s -w -X main.Commit=%s -X microservice.CommitHash=%s", commit, commit), "-o", "bootstrap", ".")
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), fmt.Sprintf("GOARCH=%s", goarch(arch)), "CGO_ENABLED=0", "GOOS=linux")
 	cmd.Stdout = os.Stdout
 	cmd.Stderr = os.Stderr
 
 	if err := cmd.Run(); err != nil {
-		fmt.Println("Error building the project:", err)
-		os.Exit(1)
+		return fmt.Errorf("error building for %s: %w", arch, err)
 	}
 
+	binaryPath := filepath.Join(dir, "bootstrap")
+
 	// Ensure the binary is executable
-	if err := os.Chmod("bootstrap", 0755); err != nil {
-		fmt.Println("Error setting executable permissions on binary:", err)
-		os.Exit(1)
+	if err := os.Chmod(binaryPath, reproducibleFileMode); err != nil {
+		return fmt.Errorf("error setting executable permissions on binary: %w", err)
 	}
 
-	// Create the ZIP file
-	de.Config.SourceCodeFilename = fmt.Sprintf("%s-%s.zip", de.Config.LambdaName, getCurrentGitCommit())
-	zipFile, err := os.Create(de.Config.SourceCodeFilename)
+	return writeReproducibleZip(zipPath, map[string]string{"bootstrap": binaryPath})
+}
+
+// goarch translates a Lambda architecture into the GOARCH cross-compiles
+// for; every Lambda architecture maps onto a GOARCH of the same name
+// except "x86_64", which Go calls "amd64".
+func goarch(arch string) string {
+	if arch == "x86_64" {
+		return "amd64"
+	}
+	return arch
+}
+
+// writeReproducibleZip packages entries (zip entry name -> local file path)
+// into zipPath with sorted entries, zeroed modification times, and a fixed
+// file mode, so two builds of the same inputs produce a byte-identical
+// archive.
+func writeReproducibleZip(zipPath string, entries map[string]string) error {
+	names := make([]string, 0, len(entries))
+	for name := range entries {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	zipFile, err := os.Create(zipPath)
 	if err != nil {
-		fmt.Println("Error creating zip file:", err)
-		os.Exit(1)
+		return fmt.Errorf("error creating zip file: %w", err)
 	}
 	defer zipFile.Close()
 
 	zipWriter := zip.NewWriter(zipFile)
 
-	// Add the binary to the ZIP file
-	binaryFile, err := os.Open("bootstrap")
-	if err != nil {
-		fmt.Println("Error opening binary file:", err)
-		os.Exit(1)
+	for _, name := range names {
+		if err := addReproducibleZipEntry(zipWriter, name, entries[name]); err != nil {
+			return err
+		}
+	}
+
+	if err := zipWriter.Close(); err != nil {
+		return fmt.Errorf("error finalizing zip file: %w", err)
 	}
-	defer binaryFile.Close()
+	return nil
+}
 
-	w, err := zipWriter.Create("bootstrap")
+func addReproducibleZipEntry(zipWriter *zip.Writer, name, path string) error {
+	file, err := os.Open(path)
 	if err != nil {
-		fmt.Println("Error creating zip entry:", err)
-		os.Exit(1)
+		return fmt.Errorf("error opening %s: %w", path, err)
 	}
+	defer file.Close()
+
+	header := &zip.FileHeader{Name: name, Method: zip.Deflate}
+	header.SetMode(reproducibleFileMode)
+	// header.Modified is left at its zero value so the entry's timestamp
+	// never varies between builds of the same commit.
 
-	if _, err := io.Copy(w, binaryFile); err != nil {
-		fmt.Println("Error writing binary to zip:", err)
-		os.Exit(1)
+	w, err := zipWriter.CreateHeader(header)
+	if err != nil {
+		return fmt.Errorf("error creating zip entry %s: %w", name, err)
 	}
 
-	// Ensure all data is written to the ZIP file
-	if err := zipWriter.Close(); err != nil {
-		fmt.Println("Error finalizing zip file:", err)
-		os.Exit(1)
+	if _, err := io.Copy(w, file); err != nil {
+		return fmt.Errorf("error writing %s to zip: %w", name, err)
 	}
+	return nil
 }
 
-func getCurrentGitCommit() string {
-	commit, err := exec.Command("git", "rev-parse", "HEAD").Output()
+func fileSHA256(path string) (string, error) {
+	file, err := os.Open(path)
 	if err != nil {
-		fmt.Println("Error getting current Git commit:", err)
-		os.Exit(1)
+		return "", fmt.Errorf("error opening %s for hashing: %w", path, err)
 	}
+	defer file.Close()
 
-	// Check for uncommitted changes
-	status, err := exec.Command("git", "status", "--porcelain").Output()
-	if err != nil {
-		fmt.Println("Error checking git status:", err)
-		os.Exit(1)
+	return hashSHA256(file)
+}
+
+func hashSHA256(r io.Reader) (string, error) {
+	h := sha256.New()
+	if _, err := io.Copy(h, r); err != nil {
+		return "", fmt.Errorf("error hashing: %w", err)
 	}
+	return fmt.Sprintf("%x", h.Sum(nil)), nil
+}
 
-	commitStr := strings.TrimSpace(string(commit))[0:7]
-	if len(status) > 0 {
-		commitStr += "-dirty"
+// cachedArtifactSHA256 hashes a build.cache_hit artifact straight from
+// Storage, since on a cache hit the zip was never rebuilt and may not
+// exist in the current working directory.
+func (de *Deployer) cachedArtifactSHA256(ctx context.Context, key string) (string, error) {
+	r, err := de.Storage.Get(ctx, key)
+	if err != nil {
+		return "", fmt.Errorf("error reading cached artifact %s: %w", key, err)
 	}
+	defer r.Close()
 
-	return commitStr
+	return hashSHA256(r)
 }
 
-func (de *Deployer) determineFunctionArch() string {
+func (de *Deployer) determineFunctionArch(ctx context.Context) (string, error) {
 	input := &lambda.GetFunctionConfigurationInput{
 		FunctionName: aws.String(de.Config.LambdaName),
 	}
 
-	output, err := de.LambdaClient.GetFunctionConfiguration(context.Background(), input)
+	output, err := de.LambdaClient.GetFunctionConfiguration(ctx, input)
 	if err != nil {
-		fmt.Println("Error getting function configuration:", err)
-		os.Exit(1)
+		return "", fmt.Errorf("error getting function configuration: %w", err)
 	}
 
-	fmt.Println("Architecture: ", output.Architectures)
-	if output.Architectures[0] == "arm64" {
-		return "arm64"
+	if len(output.Architectures) > 0 && output.Architectures[0] == lambdatypes.ArchitectureArm64 {
+		return "arm64", nil
 	}
-	return "amd64"
+	return "x86_64", nil
 }
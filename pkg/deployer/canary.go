@@ -0,0 +1,151 @@
+package deployer
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatch"
+	cwtypes "github.com/aws/aws-sdk-go-v2/service/cloudwatch/types"
+	"github.com/aws/aws-sdk-go-v2/service/lambda"
+	lambdatypes "github.com/aws/aws-sdk-go-v2/service/lambda/types"
+)
+
+const defaultPreviousVersionAlias = "stable"
+
+// DeployCanary publishes a new Lambda version and progressively shifts
+// traffic from Config.Canary.PreviousVersionAlias onto it, step by step,
+// rolling back to the version the alias pointed to before the rollout
+// started if any of Config.Canary.AlarmNames is in ALARM state when it is
+// checked at the end of a step's hold.
+// On success, the alias is promoted fully to the new version.
+func (de *Deployer) DeployCanary() error {
+	strategy := de.Config.Canary
+	previousAlias := strategy.PreviousVersionAlias
+	if previousAlias == "" {
+		previousAlias = defaultPreviousVersionAlias
+	}
+
+	publishInput := &lambda.PublishVersionInput{
+		FunctionName: aws.String(de.Config.LambdaName),
+	}
+	if de.Config.BuildSHA256 != "" {
+		publishInput.Description = aws.String(fmt.Sprintf("sha256:%s", de.Config.BuildSHA256))
+	}
+
+	publishOutput, err := de.LambdaClient.PublishVersion(context.Background(), publishInput)
+	if err != nil {
+		return fmt.Errorf("error publishing new Lambda version: %w", err)
+	}
+	newVersion := *publishOutput.Version
+
+	aliasOutput, err := de.LambdaClient.GetAlias(context.Background(), &lambda.GetAliasInput{
+		FunctionName: aws.String(de.Config.LambdaName),
+		Name:         aws.String(previousAlias),
+	})
+	if err != nil {
+		return fmt.Errorf("error looking up alias %q: %w", previousAlias, err)
+	}
+	previousVersion := *aliasOutput.FunctionVersion
+
+	for _, step := range strategy.Steps {
+		if err := de.shiftCanaryTraffic(previousAlias, previousVersion, newVersion, step.Percent); err != nil {
+			return err
+		}
+		de.log().Info().Str("alias", previousAlias).Str("version", newVersion).Int("percent", step.Percent).Dur("hold", step.Hold).Msg("canary.step")
+
+		time.Sleep(step.Hold)
+
+		alarmed, err := de.anyAlarmTriggered(strategy.AlarmNames)
+		if err != nil {
+			return fmt.Errorf("error checking CloudWatch alarms: %w", err)
+		}
+		if alarmed {
+			if rollbackErr := de.shiftCanaryTraffic(previousAlias, previousVersion, previousVersion, 0); rollbackErr != nil {
+				return fmt.Errorf("alarm triggered during %d%% step, rollback also failed: %w", step.Percent, rollbackErr)
+			}
+			return fmt.Errorf("alarm triggered during %d%% step, rolled alias %q back to version %s", step.Percent, previousAlias, previousVersion)
+		}
+	}
+
+	if _, err := de.LambdaClient.UpdateAlias(context.Background(), &lambda.UpdateAliasInput{
+		FunctionName:    aws.String(de.Config.LambdaName),
+		Name:            aws.String(previousAlias),
+		FunctionVersion: aws.String(newVersion),
+	}); err != nil {
+		return fmt.Errorf("error promoting alias %q to version %s: %w", previousAlias, newVersion, err)
+	}
+
+	de.log().Info().Str("alias", previousAlias).Str("version", newVersion).Msg("canary.promoted")
+
+	manifest, err := de.newManifest(newVersion, previousAlias)
+	if err != nil {
+		de.log().Error().Err(err).Msg("history.manifest_failed")
+		return nil
+	}
+	if err := de.recordManifest(context.Background(), manifest); err != nil {
+		de.log().Error().Err(err).Msg("history.record_failed")
+	}
+	return nil
+}
+
+// shiftCanaryTraffic points alias at baseVersion, optionally weighting
+// percent of traffic to newVersion via RoutingConfig; a 100% step points
+// it at newVersion instead (see canaryAliasInput).
+func (de *Deployer) shiftCanaryTraffic(alias, baseVersion, newVersion string, percent int) error {
+	input := canaryAliasInput(de.Config.LambdaName, alias, baseVersion, newVersion, percent)
+
+	if _, err := de.LambdaClient.UpdateAlias(context.Background(), input); err != nil {
+		return fmt.Errorf("error updating alias %q routing config: %w", alias, err)
+	}
+	return nil
+}
+
+// canaryAliasInput builds the UpdateAliasInput for one canary step. A 100%
+// (or higher) step is a full promotion to newVersion: AWS rejects
+// RoutingConfig.AdditionalVersionWeights that sum to >= 1.0, so that case
+// points the alias directly at newVersion with no RoutingConfig, instead
+// of weighting traffic onto it.
+func canaryAliasInput(functionName, alias, baseVersion, newVersion string, percent int) *lambda.UpdateAliasInput {
+	input := &lambda.UpdateAliasInput{
+		FunctionName: aws.String(functionName),
+		Name:         aws.String(alias),
+	}
+
+	switch {
+	case percent >= 100:
+		input.FunctionVersion = aws.String(newVersion)
+	case percent > 0:
+		input.FunctionVersion = aws.String(baseVersion)
+		input.RoutingConfig = &lambdatypes.AliasRoutingConfiguration{
+			AdditionalVersionWeights: map[string]float64{
+				newVersion: float64(percent) / 100,
+			},
+		}
+	default:
+		input.FunctionVersion = aws.String(baseVersion)
+	}
+
+	return input
+}
+
+func (de *Deployer) anyAlarmTriggered(alarmNames []string) (bool, error) {
+	if len(alarmNames) == 0 {
+		return false, nil
+	}
+
+	output, err := de.CloudwatchAlarmClient.DescribeAlarms(context.Background(), &cloudwatch.DescribeAlarmsInput{
+		AlarmNames: alarmNames,
+	})
+	if err != nil {
+		return false, err
+	}
+
+	for _, alarm := range output.MetricAlarms {
+		if alarm.StateValue == cwtypes.StateValueAlarm {
+			return true, nil
+		}
+	}
+	return false, nil
+}
@@ -0,0 +1,45 @@
+package deployer
+
+import (
+	"testing"
+
+	lambdatypes "github.com/aws/aws-sdk-go-v2/service/lambda/types"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCanaryAliasInput(t *testing.T) {
+	tests := []struct {
+		name            string
+		percent         int
+		wantVersion     string
+		wantRoutingConf *lambdatypes.AliasRoutingConfiguration
+	}{
+		{
+			name:        "0 percent stays on the base version",
+			percent:     0,
+			wantVersion: "1",
+		},
+		{
+			name:        "partial percent weights traffic via RoutingConfig",
+			percent:     50,
+			wantVersion: "1",
+			wantRoutingConf: &lambdatypes.AliasRoutingConfiguration{
+				AdditionalVersionWeights: map[string]float64{"2": 0.5},
+			},
+		},
+		{
+			name:        "100 percent promotes directly with no RoutingConfig",
+			percent:     100,
+			wantVersion: "2",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			input := canaryAliasInput("myfunc", "stable", "1", "2", tt.percent)
+			require.Equal(t, "myfunc", *input.FunctionName)
+			require.Equal(t, "stable", *input.Name)
+			require.Equal(t, tt.wantVersion, *input.FunctionVersion)
+			require.Equal(t, tt.wantRoutingConf, input.RoutingConfig)
+		})
+	}
+}
@@ -3,50 +3,73 @@ package deployer
 import (
 	"context"
 	"fmt"
-	"os"
 	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
-	"github.com/aws/aws-sdk-go-v2/feature/s3/manager"
 	"github.com/aws/aws-sdk-go-v2/service/lambda"
-	"github.com/aws/aws-sdk-go-v2/service/s3"
-	"github.com/aws/aws-sdk-go-v2/service/s3/types"
 )
 
-func (de *Deployer) Deploy() {
-	de.uploadToS3()
+func (de *Deployer) Deploy() error {
+	source := de.NewArtifactSource()
+
+	artifact, err := source.Package(context.Background())
+	if err != nil {
+		return err
+	}
+
+	location, err := source.Publish(context.Background(), artifact)
+	if err != nil {
+		return err
+	}
+
+	return de.DeployFromLocation(location)
+}
+
+// DeployFromLocation points the Lambda function at an already-published
+// code location and promotes it, skipping the Package/Publish steps of
+// Deploy. This is what S3Watcher uses for artifacts built and uploaded
+// out-of-band.
+func (de *Deployer) DeployFromLocation(location LambdaCodeLocation) error {
+	start := time.Now()
 
 	input := &lambda.UpdateFunctionCodeInput{
 		FunctionName: aws.String(de.Config.LambdaName),
-		S3Bucket:     aws.String(de.Config.BuildsBucket),
-		S3Key:        aws.String(de.Config.SourceCodeFilename),
+	}
+	switch {
+	case location.ImageUri != "":
+		input.ImageUri = aws.String(location.ImageUri)
+	case location.ZipFile != nil:
+		input.ZipFile = location.ZipFile
+	default:
+		input.S3Bucket = aws.String(location.S3Bucket)
+		input.S3Key = aws.String(location.S3Key)
 	}
 
 	_, err := de.LambdaClient.UpdateFunctionCode(context.Background(), input)
 	if err != nil {
-		fmt.Println("Error updating Lambda function code:", err)
-		os.Exit(1)
+		return fmt.Errorf("error updating Lambda function code: %w", err)
 	}
 
-	fmt.Println("Waiting for the function to be updated...")
+	de.log().Info().Str("function", de.Config.LambdaName).Msg("deploy.waiting")
 
 	waiter := lambda.NewFunctionUpdatedV2Waiter(de.LambdaClient)
 	err = waiter.Wait(context.Background(), &lambda.GetFunctionInput{
 		FunctionName: aws.String(de.Config.LambdaName),
 	}, 5*time.Minute)
 	if err != nil {
-		fmt.Println("Error waiting for function update:", err)
-		os.Exit(1)
+		return fmt.Errorf("error waiting for function update: %w", err)
 	}
 
 	publishInput := &lambda.PublishVersionInput{
 		FunctionName: aws.String(de.Config.LambdaName),
 	}
+	if de.Config.BuildSHA256 != "" {
+		publishInput.Description = aws.String(fmt.Sprintf("sha256:%s", de.Config.BuildSHA256))
+	}
 
 	publishOutput, err := de.LambdaClient.PublishVersion(context.Background(), publishInput)
 	if err != nil {
-		fmt.Println("Error publishing new Lambda version:", err)
-		os.Exit(1)
+		return fmt.Errorf("error publishing new Lambda version: %w", err)
 	}
 
 	updateAliasInput := &lambda.UpdateAliasInput{
@@ -57,35 +80,24 @@ func (de *Deployer) Deploy() {
 
 	_, err = de.LambdaClient.UpdateAlias(context.Background(), updateAliasInput)
 	if err != nil {
-		fmt.Println("Error updating Lambda alias 'canary':", err)
-		os.Exit(1)
+		return fmt.Errorf("error updating Lambda alias 'canary': %w", err)
 	}
 
-	fmt.Printf("Published new version %s and updated alias 'canary' to point to it\n", *publishOutput.Version)
-}
+	de.log().Info().
+		Str("version", *publishOutput.Version).
+		Str("alias", "canary").
+		Int64("duration_ms", time.Since(start).Milliseconds()).
+		Msg("lambda.publish")
 
-func (de *Deployer) uploadToS3() {
-	zipFile, err := os.Open(de.Config.SourceCodeFilename)
+	manifest, err := de.newManifest(*publishOutput.Version, "canary")
 	if err != nil {
-		fmt.Println("Error opening zip file:", err)
-		os.Exit(1)
+		de.log().Error().Err(err).Msg("history.manifest_failed")
+		return nil
 	}
-	defer zipFile.Close()
-
-	uploader := manager.NewUploader(de.S3Client)
-	_, err = uploader.Upload(context.TODO(), &s3.PutObjectInput{
-		Bucket:               aws.String(de.Config.BuildsBucket),
-		Key:                  aws.String(de.Config.SourceCodeFilename),
-		Body:                 zipFile,
-		ServerSideEncryption: types.ServerSideEncryptionAes256,
-	})
-	if err != nil {
-		fmt.Println("Error uploading zip to S3:", err)
-		os.Exit(1)
+	manifest.ArtifactKey = location.S3Key
+	manifest.ImageURI = location.ImageUri
+	if err := de.recordManifest(context.Background(), manifest); err != nil {
+		de.log().Error().Err(err).Msg("history.record_failed")
 	}
-	// if err := os.Remove(de.Config.SourceCodeFilename); err != nil {
-	// 	fmt.Println("Error deleting zip file:", err)
-	// }
-
-	fmt.Printf("Released %s to %s\n", de.Config.SourceCodeFilename, de.Config.BuildsBucket)
+	return nil
 }
@@ -3,35 +3,169 @@ package deployer
 import (
 	"context"
 	"fmt"
-	"os"
+	"strings"
 
 	"github.com/alex-roman/golang-lambda-deployer/pkg"
+	"github.com/alex-roman/golang-lambda-deployer/pkg/blob"
+	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/credentials/stscreds"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatch"
 	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs"
 	"github.com/aws/aws-sdk-go-v2/service/lambda"
 	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+	"github.com/rs/zerolog"
 )
 
+// defaultRegionCandidates is scanned by DiscoverFunctionRegion when
+// Config.RegionCandidates is empty.
+var defaultRegionCandidates = []string{"us-east-1", "us-west-2", "eu-west-1"}
+
 type Deployer struct {
-	Config           pkg.DeployConfig
-	LambdaClient     *lambda.Client
-	CloudwatchClient *cloudwatchlogs.Client
-	S3Client         *s3.Client
+	Config                pkg.DeployConfig
+	LambdaClient          *lambda.Client
+	CloudwatchClient      *cloudwatchlogs.Client
+	CloudwatchAlarmClient *cloudwatch.Client
+	S3Client              *s3.Client
+	SQSClient             *sqs.Client
+	// Storage is where build artifacts are published and looked up,
+	// selected from Config.BuildsBucket's URL scheme (s3://, gs://,
+	// file://). Initialized by InitAWSClient.
+	Storage blob.Storage
+	// Source resolves the commit Build packages and the directory it
+	// builds from. Defaults to pkg.LocalSource{} (today's working tree)
+	// when nil.
+	Source pkg.Source
+	// Logger receives structured events from the deployer. Library code
+	// never calls os.Exit; callers (e.g. deployer/main.go) decide what to do
+	// with a returned error. A nil Logger is treated as a no-op logger.
+	Logger *zerolog.Logger
+
+	// resolvedCommit/resolvedDirty cache the Source.Resolve result from
+	// the most recent Build/BuildMulti/ECRImageSource.Package call, so
+	// newManifest doesn't resolve Source a second time (which, for a
+	// GitSource, would clone the repo again into a second checkout
+	// directory).
+	resolvedCommit string
+	resolvedDirty  bool
+	sourceResolved bool
+}
+
+// source returns Source, falling back to pkg.LocalSource{} so Build can
+// always resolve a commit even when a caller never set one.
+func (de *Deployer) source() pkg.Source {
+	if de.Source != nil {
+		return de.Source
+	}
+	return pkg.LocalSource{}
 }
 
-func (de *Deployer) InitAWSClient() {
-	cfg, err := config.LoadDefaultConfig(context.Background(), config.WithRegion("us-east-1"))
+// rememberResolved caches the result of a Source.Resolve call Build,
+// BuildMulti, or ECRImageSource.Package already made, for newManifest to
+// reuse.
+func (de *Deployer) rememberResolved(commit string, dirty bool) {
+	de.resolvedCommit = commit
+	de.resolvedDirty = dirty
+	de.sourceResolved = true
+}
+
+// resolvedSource returns the commit/dirty state Build/BuildMulti/Package
+// already resolved Source to, if any, instead of resolving it again. When
+// nothing has resolved it yet (e.g. Rollback, which doesn't build), it
+// resolves Source once here and closes it immediately afterward.
+func (de *Deployer) resolvedSource(ctx context.Context) (string, bool, error) {
+	if de.sourceResolved {
+		return de.resolvedCommit, de.resolvedDirty, nil
+	}
+
+	source := de.source()
+	defer func() {
+		if err := source.Close(); err != nil {
+			de.log().Warn().Err(err).Msg("build.cleanup_failed")
+		}
+	}()
+
+	return source.Resolve(ctx)
+}
+
+func (de *Deployer) InitAWSClient() error {
+	cfg, err := de.loadAWSConfig(de.Config.Region)
 	if err != nil {
-		fmt.Println("Error loading AWS configuration:", err)
-		os.Exit(1)
+		return fmt.Errorf("error loading AWS configuration: %w", err)
 	}
 
 	de.CloudwatchClient = cloudwatchlogs.NewFromConfig(cfg)
+	de.CloudwatchAlarmClient = cloudwatch.NewFromConfig(cfg)
 	de.LambdaClient = lambda.NewFromConfig(cfg)
 	de.S3Client = s3.NewFromConfig(cfg)
+	de.SQSClient = sqs.NewFromConfig(cfg)
+
+	storage, err := blob.New(context.Background(), de.Config.BuildsBucket, de.S3Client)
+	if err != nil {
+		return fmt.Errorf("error initializing blob storage: %w", err)
+	}
+	de.Storage = storage
+
+	return nil
+}
+
+func (de *Deployer) loadAWSConfig(region string) (aws.Config, error) {
+	if region == "" {
+		region = "us-east-1"
+	}
+
+	opts := []func(*config.LoadOptions) error{config.WithRegion(region)}
+	if de.Config.Profile != "" {
+		opts = append(opts, config.WithSharedConfigProfile(de.Config.Profile))
+	}
+
+	cfg, err := config.LoadDefaultConfig(context.Background(), opts...)
+	if err != nil {
+		return aws.Config{}, err
+	}
+
+	if de.Config.RoleARN != "" {
+		stsClient := sts.NewFromConfig(cfg)
+		cfg.Credentials = aws.NewCredentialsCache(stscreds.NewAssumeRoleProvider(stsClient, de.Config.RoleARN))
+	}
+
+	return cfg, nil
+}
+
+// DiscoverFunctionRegion scans Config.RegionCandidates (falling back to
+// defaultRegionCandidates when unset) for the region hosting LambdaName,
+// the same way AWS SDK credential/region resolvers fall back to probing
+// EC2 instance metadata when no region is explicitly configured. On a
+// match it updates Config.Region and re-initializes the AWS clients.
+func (de *Deployer) DiscoverFunctionRegion() error {
+	candidates := de.Config.RegionCandidates
+	if len(candidates) == 0 {
+		candidates = defaultRegionCandidates
+	}
+
+	for _, region := range candidates {
+		cfg, err := de.loadAWSConfig(region)
+		if err != nil {
+			continue
+		}
+
+		client := lambda.NewFromConfig(cfg)
+		_, err = client.GetFunction(context.Background(), &lambda.GetFunctionInput{
+			FunctionName: aws.String(de.Config.LambdaName),
+		})
+		if err == nil {
+			de.log().Info().Str("region", region).Str("function", de.Config.LambdaName).Msg("region.discovered")
+			de.Config.Region = region
+			return de.InitAWSClient()
+		}
+	}
+
+	return fmt.Errorf("function %s not found in any candidate region: %s", de.Config.LambdaName, strings.Join(candidates, ", "))
 }
 
-func (de *Deployer) GetAvailableFunctions() []string {
+func (de *Deployer) GetAvailableFunctions() ([]string, error) {
 	var functionNames []string
 	var nextMarker *string
 
@@ -41,8 +175,7 @@ func (de *Deployer) GetAvailableFunctions() []string {
 		}
 		output, err := de.LambdaClient.ListFunctions(context.Background(), input)
 		if err != nil {
-			fmt.Println("Error listing Lambda functions:", err)
-			os.Exit(1)
+			return nil, fmt.Errorf("error listing Lambda functions: %w", err)
 		}
 
 		for _, function := range output.Functions {
@@ -55,20 +188,19 @@ func (de *Deployer) GetAvailableFunctions() []string {
 		nextMarker = output.NextMarker
 	}
 
-	return functionNames
+	return functionNames, nil
 }
 
-func (de *Deployer) GetAvailableBuckets() []string {
+func (de *Deployer) GetAvailableBuckets() ([]string, error) {
 	input := &s3.ListBucketsInput{}
 	output, err := de.S3Client.ListBuckets(context.Background(), input)
 	if err != nil {
-		fmt.Println("Error listing S3 buckets:", err)
-		os.Exit(1)
+		return nil, fmt.Errorf("error listing S3 buckets: %w", err)
 	}
 
 	var bucketNames []string
 	for _, bucket := range output.Buckets {
 		bucketNames = append(bucketNames, *bucket.Name)
 	}
-	return bucketNames
+	return bucketNames, nil
 }
@@ -0,0 +1,217 @@
+package deployer
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/lambda"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// DeployManifest records a single deploy (or rollback) so Deployer.ListHistory
+// and the "deploy rollback" command have a real audit trail to work from,
+// without hunting through Lambda console versions.
+type DeployManifest struct {
+	Commit      string    `json:"commit"`
+	Dirty       bool      `json:"dirty"`
+	Version     string    `json:"version"`
+	Alias       string    `json:"alias"`
+	ArtifactKey string    `json:"artifact_key,omitempty"`
+	ImageURI    string    `json:"image_uri,omitempty"`
+	DeployedBy  string    `json:"deployed_by"`
+	DeployedAt  time.Time `json:"deployed_at"`
+	Arch        string    `json:"arch"`
+	ConfigHash  string    `json:"config_hash"`
+	Rollback    bool      `json:"rollback,omitempty"`
+}
+
+func (de *Deployer) historyPrefix() string {
+	return fmt.Sprintf("%s-%s/history/", de.Config.AppName, de.Config.Env)
+}
+
+// recordManifest writes manifest to
+// s3://<BuildsBucket>/<AppName>-<Env>/history/<timestamp>-<commit>.json.
+// Its timestamp prefix is UTC and lexically sortable, so ListHistory can
+// page back through keys without parsing each object first.
+func (de *Deployer) recordManifest(ctx context.Context, manifest DeployManifest) error {
+	data, err := json.Marshal(manifest)
+	if err != nil {
+		return fmt.Errorf("error marshaling deploy manifest: %w", err)
+	}
+
+	key := fmt.Sprintf("%s%s-%s.json", de.historyPrefix(), manifest.DeployedAt.UTC().Format("20060102T150405Z"), manifest.Commit)
+	_, err = de.S3Client.PutObject(ctx, &s3.PutObjectInput{
+		Bucket: aws.String(de.Config.BuildsBucket),
+		Key:    aws.String(key),
+		Body:   bytes.NewReader(data),
+	})
+	if err != nil {
+		return fmt.Errorf("error writing deploy manifest: %w", err)
+	}
+
+	de.log().Info().Str("key", key).Msg("history.recorded")
+	return nil
+}
+
+// newManifest fills in the fields common to every manifest: who deployed,
+// when, from what commit/arch, and a hash of the config used.
+func (de *Deployer) newManifest(version, alias string) (DeployManifest, error) {
+	ctx := context.Background()
+
+	commit, dirty, err := de.resolvedSource(ctx)
+	if err != nil {
+		return DeployManifest{}, err
+	}
+
+	arch, err := de.determineFunctionArch(ctx)
+	if err != nil {
+		return DeployManifest{}, err
+	}
+
+	return DeployManifest{
+		Commit:     commit,
+		Dirty:      dirty,
+		Version:    version,
+		Alias:      alias,
+		DeployedBy: deployedBy(),
+		DeployedAt: time.Now().UTC(),
+		Arch:       arch,
+		ConfigHash: hashConfig(de.Config),
+	}, nil
+}
+
+func deployedBy() string {
+	if user := os.Getenv("USER"); user != "" {
+		return user
+	}
+	return "unknown"
+}
+
+func hashConfig(cfg interface{}) string {
+	data, err := json.Marshal(cfg)
+	if err != nil {
+		return ""
+	}
+	sum := sha256.Sum256(data)
+	return fmt.Sprintf("%x", sum)
+}
+
+// ListHistory returns up to limit DeployManifest entries for this
+// AppName/Env, newest first.
+func (de *Deployer) ListHistory(limit int) ([]DeployManifest, error) {
+	var keys []string
+	var continuationToken *string
+	for {
+		output, err := de.S3Client.ListObjectsV2(context.Background(), &s3.ListObjectsV2Input{
+			Bucket:            aws.String(de.Config.BuildsBucket),
+			Prefix:            aws.String(de.historyPrefix()),
+			ContinuationToken: continuationToken,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("error listing deploy history: %w", err)
+		}
+
+		for _, obj := range output.Contents {
+			keys = append(keys, *obj.Key)
+		}
+
+		if !aws.ToBool(output.IsTruncated) {
+			break
+		}
+		continuationToken = output.NextContinuationToken
+	}
+	sort.Sort(sort.Reverse(sort.StringSlice(keys)))
+
+	if limit > 0 && len(keys) > limit {
+		keys = keys[:limit]
+	}
+
+	manifests := make([]DeployManifest, 0, len(keys))
+	for _, key := range keys {
+		manifest, err := de.readManifest(key)
+		if err != nil {
+			de.log().Error().Err(err).Str("key", key).Msg("history.read_failed")
+			continue
+		}
+		manifests = append(manifests, manifest)
+	}
+
+	return manifests, nil
+}
+
+func (de *Deployer) readManifest(key string) (DeployManifest, error) {
+	output, err := de.S3Client.GetObject(context.Background(), &s3.GetObjectInput{
+		Bucket: aws.String(de.Config.BuildsBucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		return DeployManifest{}, fmt.Errorf("error reading deploy manifest %s: %w", key, err)
+	}
+	defer output.Body.Close()
+
+	data, err := io.ReadAll(output.Body)
+	if err != nil {
+		return DeployManifest{}, fmt.Errorf("error reading deploy manifest body %s: %w", key, err)
+	}
+
+	var manifest DeployManifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return DeployManifest{}, fmt.Errorf("error decoding deploy manifest %s: %w", key, err)
+	}
+	return manifest, nil
+}
+
+// Rollback finds the most recent manifest whose Version or Commit matches
+// target and points alias at its version without republishing, recording a
+// new manifest entry marking the rollback.
+func (de *Deployer) Rollback(target, alias string) error {
+	manifests, err := de.ListHistory(0)
+	if err != nil {
+		return err
+	}
+
+	var found *DeployManifest
+	for i := range manifests {
+		if manifests[i].Version == target || manifests[i].Commit == target {
+			found = &manifests[i]
+			break
+		}
+	}
+	if found == nil {
+		return fmt.Errorf("no deploy manifest found matching %q", target)
+	}
+
+	_, err = de.LambdaClient.UpdateAlias(context.Background(), &lambda.UpdateAliasInput{
+		FunctionName:    aws.String(de.Config.LambdaName),
+		Name:            aws.String(alias),
+		FunctionVersion: aws.String(found.Version),
+	})
+	if err != nil {
+		return fmt.Errorf("error rolling back alias %q to version %s: %w", alias, found.Version, err)
+	}
+
+	manifest, err := de.newManifest(found.Version, alias)
+	if err != nil {
+		return err
+	}
+	manifest.Commit = found.Commit
+	manifest.Dirty = found.Dirty
+	manifest.ArtifactKey = found.ArtifactKey
+	manifest.ImageURI = found.ImageURI
+	manifest.Rollback = true
+
+	if err := de.recordManifest(context.Background(), manifest); err != nil {
+		de.log().Error().Err(err).Msg("history.rollback_record_failed")
+	}
+
+	de.log().Info().Str("alias", alias).Str("version", found.Version).Msg("rollback.complete")
+	return nil
+}
@@ -0,0 +1,36 @@
+package deployer
+
+import (
+	"io"
+	"os"
+	"strings"
+
+	"github.com/rs/zerolog"
+)
+
+// NewLogger builds a zerolog.Logger from a --log-level name
+// (debug|info|warn|error, defaulting to info) and a --log-format name
+// (json|console, defaulting to console).
+func NewLogger(level, format string) zerolog.Logger {
+	var writer io.Writer = zerolog.ConsoleWriter{Out: os.Stdout, TimeFormat: "15:04:05"}
+	if strings.EqualFold(format, "json") {
+		writer = os.Stdout
+	}
+
+	zlevel, err := zerolog.ParseLevel(strings.ToLower(level))
+	if err != nil || zlevel == zerolog.NoLevel {
+		zlevel = zerolog.InfoLevel
+	}
+
+	return zerolog.New(writer).Level(zlevel).With().Timestamp().Logger()
+}
+
+// log returns Logger, falling back to a no-op logger so library code can
+// always log unconditionally even when a caller never set one.
+func (de *Deployer) log() *zerolog.Logger {
+	if de.Logger != nil {
+		return de.Logger
+	}
+	nop := zerolog.Nop()
+	return &nop
+}
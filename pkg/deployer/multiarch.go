@@ -0,0 +1,179 @@
+package deployer
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/lambda"
+	lambdatypes "github.com/aws/aws-sdk-go-v2/service/lambda/types"
+)
+
+// MultiArchArtifact is one architecture's build output from BuildMulti.
+type MultiArchArtifact struct {
+	Arch    string
+	ZipPath string
+	SHA256  string
+}
+
+// BuildMulti cross-compiles the function once per arch in archs (falling
+// back to Config.Architectures when archs is empty), producing
+// "<LambdaName>-<commit>-<arch>.zip" for each and uploading it to Storage.
+func (de *Deployer) BuildMulti(ctx context.Context, archs []string) ([]MultiArchArtifact, error) {
+	if len(archs) == 0 {
+		archs = de.Config.Architectures
+	}
+	if len(archs) == 0 {
+		return nil, fmt.Errorf("no architectures requested")
+	}
+
+	source := de.source()
+	defer func() {
+		if err := source.Close(); err != nil {
+			de.log().Warn().Err(err).Msg("build.cleanup_failed")
+		}
+	}()
+
+	commit, dirty, err := source.Resolve(ctx)
+	if err != nil {
+		return nil, err
+	}
+	de.rememberResolved(commit, dirty)
+
+	artifacts := make([]MultiArchArtifact, 0, len(archs))
+	for _, arch := range archs {
+		zipPath := fmt.Sprintf("%s-%s-%s.zip", de.Config.LambdaName, commit, arch)
+
+		de.log().Info().Str("commit", commit).Str("arch", arch).Msg("build.start")
+		if err := de.compileArch(commit, arch, zipPath); err != nil {
+			return nil, err
+		}
+
+		sum, err := fileSHA256(zipPath)
+		if err != nil {
+			return nil, err
+		}
+
+		if err := de.uploadArtifact(ctx, zipPath); err != nil {
+			return nil, err
+		}
+
+		de.log().Info().Str("arch", arch).Str("file", zipPath).Str("sha256", sum).Msg("build.multiarch")
+		artifacts = append(artifacts, MultiArchArtifact{Arch: arch, ZipPath: zipPath, SHA256: sum})
+	}
+
+	return artifacts, nil
+}
+
+func (de *Deployer) uploadArtifact(ctx context.Context, zipPath string) error {
+	file, err := os.Open(zipPath)
+	if err != nil {
+		return fmt.Errorf("error opening %s: %w", zipPath, err)
+	}
+	defer file.Close()
+
+	if err := de.Storage.Put(ctx, zipPath, file); err != nil {
+		return fmt.Errorf("error uploading %s: %w", zipPath, err)
+	}
+	return nil
+}
+
+// DeployMultiArch publishes the artifacts BuildMulti produced. Lambda can
+// only run a version on one architecture today, so this first attempts a
+// single version spanning every requested architecture and, when Lambda
+// rejects that, falls back to publishing one version per architecture and
+// aliasing each as "<alias>-<arch>".
+func (de *Deployer) DeployMultiArch(ctx context.Context, artifacts []MultiArchArtifact, alias string) error {
+	if len(artifacts) == 0 {
+		return fmt.Errorf("no artifacts to deploy")
+	}
+	if alias == "" {
+		alias = "canary"
+	}
+
+	if len(artifacts) > 1 {
+		if err := de.publishSingleMultiArchVersion(ctx, artifacts, alias); err == nil {
+			return nil
+		} else {
+			de.log().Warn().Err(err).Msg("multiarch.single_version_unsupported")
+		}
+	}
+
+	return de.publishPerArchAliases(ctx, artifacts, alias)
+}
+
+func (de *Deployer) publishSingleMultiArchVersion(ctx context.Context, artifacts []MultiArchArtifact, alias string) error {
+	archTypes := make([]lambdatypes.Architecture, 0, len(artifacts))
+	for _, artifact := range artifacts {
+		archTypes = append(archTypes, lambdatypes.Architecture(artifact.Arch))
+	}
+
+	primary := artifacts[0]
+	if err := de.waitAndPublish(ctx, primary.ZipPath, alias, archTypes); err != nil {
+		return err
+	}
+
+	de.log().Info().Strs("archs", archNames(artifacts)).Str("alias", alias).Msg("multiarch.published")
+	return nil
+}
+
+func (de *Deployer) publishPerArchAliases(ctx context.Context, artifacts []MultiArchArtifact, alias string) error {
+	for _, artifact := range artifacts {
+		archAlias := fmt.Sprintf("%s-%s", alias, artifact.Arch)
+		archs := []lambdatypes.Architecture{lambdatypes.Architecture(artifact.Arch)}
+		if err := de.waitAndPublish(ctx, artifact.ZipPath, archAlias, archs); err != nil {
+			return fmt.Errorf("error publishing %s: %w", artifact.Arch, err)
+		}
+
+		de.log().Info().Str("arch", artifact.Arch).Str("alias", archAlias).Msg("multiarch.published")
+	}
+
+	return nil
+}
+
+// waitAndPublish points the function at s3Key (built for archs), waits
+// for the update to settle, publishes a new version, and points alias at
+// it. Architecture can only be changed as part of UpdateFunctionCode,
+// not through a separate UpdateFunctionConfiguration call.
+func (de *Deployer) waitAndPublish(ctx context.Context, s3Key, alias string, archs []lambdatypes.Architecture) error {
+	if _, err := de.LambdaClient.UpdateFunctionCode(ctx, &lambda.UpdateFunctionCodeInput{
+		FunctionName:  aws.String(de.Config.LambdaName),
+		S3Bucket:      aws.String(de.Config.BuildsBucket),
+		S3Key:         aws.String(s3Key),
+		Architectures: archs,
+	}); err != nil {
+		return fmt.Errorf("error updating function code: %w", err)
+	}
+
+	waiter := lambda.NewFunctionUpdatedV2Waiter(de.LambdaClient)
+	if err := waiter.Wait(ctx, &lambda.GetFunctionInput{FunctionName: aws.String(de.Config.LambdaName)}, 5*time.Minute); err != nil {
+		return fmt.Errorf("error waiting for function update: %w", err)
+	}
+
+	publishOutput, err := de.LambdaClient.PublishVersion(ctx, &lambda.PublishVersionInput{
+		FunctionName: aws.String(de.Config.LambdaName),
+	})
+	if err != nil {
+		return fmt.Errorf("error publishing version: %w", err)
+	}
+
+	if _, err := de.LambdaClient.UpdateAlias(ctx, &lambda.UpdateAliasInput{
+		FunctionName:    aws.String(de.Config.LambdaName),
+		Name:            aws.String(alias),
+		FunctionVersion: publishOutput.Version,
+	}); err != nil {
+		return fmt.Errorf("error updating alias %q: %w", alias, err)
+	}
+
+	return nil
+}
+
+func archNames(artifacts []MultiArchArtifact) []string {
+	names := make([]string, len(artifacts))
+	for i, artifact := range artifacts {
+		names[i] = artifact.Arch
+	}
+	return names
+}
@@ -2,44 +2,75 @@ package deployer
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
-	"log"
-	"os"
 	"strings"
 	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs"
 	cwtypes "github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs/types"
+	"github.com/rs/zerolog"
 )
 
-func (de *Deployer) TailLogs() {
-	availableLogGroups := de.getAvailableLogGroups()
+// structuredLogLine is the shape Lambda emits when LOG_FORMAT=JSON is set
+// on the function. Fields beyond these are re-attached to the re-emitted
+// event verbatim; see emitLogEvent.
+type structuredLogLine struct {
+	Level     string `json:"level"`
+	Timestamp string `json:"timestamp"`
+	Message   string `json:"message"`
+}
+
+// TailLogs streams live CloudWatch logs for Config.LogGroupName for five
+// minutes, re-emitting each message through Logger. Messages that decode
+// as Lambda's structured JSON log format are re-emitted at their original
+// level, with their original timestamp attached as "lambda_timestamp";
+// filterLevel (parsed with zerolog.ParseLevel) drops
+// anything below that threshold. An empty filterLevel disables filtering.
+func (de *Deployer) TailLogs(filterLevel string) error {
+	minLevel := zerolog.TraceLevel
+	if filterLevel != "" {
+		level, err := zerolog.ParseLevel(strings.ToLower(filterLevel))
+		if err != nil {
+			return fmt.Errorf("invalid --filter-level %q: %w", filterLevel, err)
+		}
+		minLevel = level
+	}
+
+	availableLogGroups, err := de.getAvailableLogGroups()
+	if err != nil {
+		return err
+	}
 	if !contains(availableLogGroups, de.Config.LogGroupName) {
-		fmt.Printf("Log group %s does not exist\n", de.Config.LogGroupName)
-		fmt.Printf("Available log groups are: %s\n", strings.Join(availableLogGroups, ", "))
-		os.Exit(1)
+		return fmt.Errorf("log group %s does not exist; available log groups are: %s", de.Config.LogGroupName, strings.Join(availableLogGroups, ", "))
+	}
+
+	logGroupARN, err := de.getARNofLogGroup()
+	if err != nil {
+		return err
 	}
 
 	request := &cloudwatchlogs.StartLiveTailInput{
-		LogGroupIdentifiers:   []string{de.getARNofLogGroup()},
+		LogGroupIdentifiers:   []string{logGroupARN},
 		LogEventFilterPattern: aws.String(`-"START RequestId" -"REPORT RequestId" -"END RequestId" -"INIT_START Runtime" -"EXTENSION"`),
 	}
 
 	response, err := de.CloudwatchClient.StartLiveTail(context.Background(), request)
 	if err != nil {
-		log.Fatalf("Failed to start streaming: %v", err)
+		return fmt.Errorf("failed to start streaming: %w", err)
 	}
 
 	stream := response.GetStream()
-	go handleEventStreamAsync(stream)
+	go de.handleEventStreamAsync(stream, minLevel)
 
 	// Close the stream (which ends the session) after a timeout
 	time.Sleep(300 * time.Second)
 	stream.Close()
+	return nil
 }
 
-func (de *Deployer) getAvailableLogGroups() []string {
+func (de *Deployer) getAvailableLogGroups() ([]string, error) {
 	var logGroupNames []string
 	var nextToken *string
 
@@ -49,8 +80,7 @@ func (de *Deployer) getAvailableLogGroups() []string {
 		}
 		output, err := de.CloudwatchClient.DescribeLogGroups(context.Background(), input)
 		if err != nil {
-			fmt.Println("Error describing log groups:", err)
-			os.Exit(1)
+			return nil, fmt.Errorf("error describing log groups: %w", err)
 		}
 
 		for _, logGroup := range output.LogGroups {
@@ -63,7 +93,7 @@ func (de *Deployer) getAvailableLogGroups() []string {
 		nextToken = output.NextToken
 	}
 
-	return logGroupNames
+	return logGroupNames, nil
 }
 
 func contains(slice []string, item string) bool {
@@ -75,47 +105,82 @@ func contains(slice []string, item string) bool {
 	return false
 }
 
-func (de *Deployer) getARNofLogGroup() string {
+func (de *Deployer) getARNofLogGroup() (string, error) {
 	input := &cloudwatchlogs.DescribeLogGroupsInput{
 		LogGroupNamePrefix: aws.String(de.Config.LogGroupName),
 	}
 
 	output, err := de.CloudwatchClient.DescribeLogGroups(context.Background(), input)
 	if err != nil {
-		fmt.Println("Error describing log groups:", err)
-		os.Exit(1)
+		return "", fmt.Errorf("error describing log groups: %w", err)
 	}
 
 	if len(output.LogGroups) == 0 {
-		fmt.Printf("No log groups found for the given prefix %s\n", de.Config.LogGroupName)
-		os.Exit(1)
+		return "", fmt.Errorf("no log groups found for the given prefix %s", de.Config.LogGroupName)
 	}
 
-	arn := strings.TrimSuffix(*output.LogGroups[0].Arn, ":*")
-	return arn
+	return strings.TrimSuffix(*output.LogGroups[0].Arn, ":*"), nil
 }
 
-func handleEventStreamAsync(stream *cloudwatchlogs.StartLiveTailEventStream) {
+func (de *Deployer) handleEventStreamAsync(stream *cloudwatchlogs.StartLiveTailEventStream, minLevel zerolog.Level) {
 	eventsChan := stream.Events()
 	for {
 		event := <-eventsChan
 		switch e := event.(type) {
 		case *cwtypes.StartLiveTailResponseStreamMemberSessionStart:
-			fmt.Println("Logs streaming session started")
+			de.log().Info().Msg("tail.session_started")
 			continue // ignore session start
 		case *cwtypes.StartLiveTailResponseStreamMemberSessionUpdate:
 			for _, logEvent := range e.Value.SessionResults {
-				log.Println(*logEvent.Message)
+				de.emitLogEvent(*logEvent.Message, minLevel)
 			}
 		default:
 			// Handle on-stream exceptions
 			if err := stream.Err(); err != nil {
-				log.Fatalf("Error occured during streaming: %v", err)
+				de.log().Error().Err(err).Msg("tail.stream_error")
 			} else if event == nil {
 				return
 			} else {
-				log.Fatalf("Unknown event type: %T", e)
+				de.log().Error().Str("type", fmt.Sprintf("%T", e)).Msg("tail.unknown_event")
 			}
 		}
 	}
 }
+
+// emitLogEvent decodes message as Lambda's structured JSON log format when
+// possible, re-emitting it through Logger at its original level, with its
+// original timestamp as a "lambda_timestamp" field and any other fields
+// attached verbatim; otherwise it logs the
+// raw message at info level. Events below minLevel are dropped.
+func (de *Deployer) emitLogEvent(message string, minLevel zerolog.Level) {
+	var line structuredLogLine
+	if err := json.Unmarshal([]byte(message), &line); err != nil || line.Message == "" {
+		if minLevel <= zerolog.InfoLevel {
+			de.log().Info().Msg(message)
+		}
+		return
+	}
+
+	level, err := zerolog.ParseLevel(strings.ToLower(line.Level))
+	if err != nil {
+		level = zerolog.InfoLevel
+	}
+	if level < minLevel {
+		return
+	}
+
+	var extra map[string]interface{}
+	_ = json.Unmarshal([]byte(message), &extra)
+	delete(extra, "level")
+	delete(extra, "timestamp")
+	delete(extra, "message")
+
+	event := de.log().WithLevel(level)
+	if line.Timestamp != "" {
+		event = event.Str("lambda_timestamp", line.Timestamp)
+	}
+	if len(extra) > 0 {
+		event = event.Fields(extra)
+	}
+	event.Msg(line.Message)
+}
@@ -0,0 +1,172 @@
+package deployer
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
+)
+
+const defaultWatchPollInterval = 30 * time.Second
+
+// S3Watcher turns the deployer into a long-running process that redeploys
+// whenever a new build artifact appears in Config.BuildsBucket, for setups
+// where CI uploads zips out-of-band and deploy is decoupled from build.
+type S3Watcher struct {
+	de           *Deployer
+	PollInterval time.Duration
+	// SQSQueueURL, when set, long-polls that queue for s3:ObjectCreated:*
+	// notifications instead of polling ListObjectsV2 directly.
+	SQSQueueURL string
+}
+
+// NewS3Watcher returns a watcher for de.Config.BuildsBucket, keyed off
+// "<AppName>-<Env>-" object prefixes. SQS long-polling uses de.SQSClient.
+func (de *Deployer) NewS3Watcher() *S3Watcher {
+	return &S3Watcher{de: de}
+}
+
+// Watch blocks, redeploying via DeployFromLocation whenever a new key
+// matching "<LambdaName>-" appears.
+func (w *S3Watcher) Watch(ctx context.Context) error {
+	if w.SQSQueueURL != "" {
+		return w.watchSQS(ctx)
+	}
+	return w.watchPoll(ctx)
+}
+
+func (w *S3Watcher) watchPoll(ctx context.Context) error {
+	interval := w.PollInterval
+	if interval <= 0 {
+		interval = defaultWatchPollInterval
+	}
+	prefix := fmt.Sprintf("%s-", w.de.Config.LambdaName)
+
+	// The first listing only records the newest existing artifact, so
+	// keys already in the bucket when Watch starts are not redeployed.
+	var newest time.Time
+	seeded := false
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		default:
+		}
+
+		output, err := w.de.S3Client.ListObjectsV2(ctx, &s3.ListObjectsV2Input{
+			Bucket: aws.String(w.de.Config.BuildsBucket),
+			Prefix: aws.String(prefix),
+		})
+		if err != nil {
+			w.de.log().Error().Err(err).Msg("watch.list_failed")
+			time.Sleep(interval)
+			continue
+		}
+
+		for _, obj := range output.Contents {
+			if obj.LastModified == nil || !obj.LastModified.After(newest) {
+				continue
+			}
+			newest = *obj.LastModified
+			if !seeded {
+				continue
+			}
+			if err := w.redeploy(*obj.Key); err != nil {
+				w.de.log().Error().Err(err).Str("key", *obj.Key).Msg("watch.redeploy_failed")
+			}
+		}
+		seeded = true
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(interval):
+		}
+	}
+}
+
+func (w *S3Watcher) watchSQS(ctx context.Context) error {
+	prefix := fmt.Sprintf("%s-", w.de.Config.LambdaName)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		default:
+		}
+
+		output, err := w.de.SQSClient.ReceiveMessage(ctx, &sqs.ReceiveMessageInput{
+			QueueUrl:            aws.String(w.SQSQueueURL),
+			MaxNumberOfMessages: 10,
+			WaitTimeSeconds:     20,
+		})
+		if err != nil {
+			w.de.log().Error().Err(err).Msg("watch.sqs_receive_failed")
+			continue
+		}
+
+		for _, msg := range output.Messages {
+			if key, ok := parseS3EventKey(*msg.Body, w.de.Config.BuildsBucket, prefix); ok {
+				if err := w.redeploy(key); err != nil {
+					w.de.log().Error().Err(err).Str("key", key).Msg("watch.redeploy_failed")
+				}
+			}
+
+			if _, err := w.de.SQSClient.DeleteMessage(ctx, &sqs.DeleteMessageInput{
+				QueueUrl:      aws.String(w.SQSQueueURL),
+				ReceiptHandle: msg.ReceiptHandle,
+			}); err != nil {
+				w.de.log().Error().Err(err).Msg("watch.sqs_delete_failed")
+			}
+		}
+	}
+}
+
+type s3EventNotification struct {
+	Records []struct {
+		EventName string `json:"eventName"`
+		S3        struct {
+			Bucket struct {
+				Name string `json:"name"`
+			} `json:"bucket"`
+			Object struct {
+				Key string `json:"key"`
+			} `json:"object"`
+		} `json:"s3"`
+	} `json:"Records"`
+}
+
+// parseS3EventKey extracts the object key from an SQS message body carrying
+// an S3 ObjectCreated event notification, filtering by bucket and key
+// prefix. It returns false if the body isn't such a notification or no
+// record matches.
+func parseS3EventKey(body, bucket, prefix string) (string, bool) {
+	var event s3EventNotification
+	if err := json.Unmarshal([]byte(body), &event); err != nil {
+		return "", false
+	}
+
+	for _, record := range event.Records {
+		if !strings.HasPrefix(record.EventName, "ObjectCreated:") {
+			continue
+		}
+		if record.S3.Bucket.Name != bucket {
+			continue
+		}
+		if strings.HasPrefix(record.S3.Object.Key, prefix) {
+			return record.S3.Object.Key, true
+		}
+	}
+	return "", false
+}
+
+func (w *S3Watcher) redeploy(key string) error {
+	w.de.log().Info().Str("key", key).Msg("watch.new_artifact")
+	w.de.Config.SourceCodeFilename = key
+	return w.de.DeployFromLocation(LambdaCodeLocation{S3Bucket: w.de.Config.BuildsBucket, S3Key: key})
+}
@@ -0,0 +1,24 @@
+package deployer
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseS3EventKey(t *testing.T) {
+	body := `{"Records":[{"eventName":"ObjectCreated:Put","s3":{"bucket":{"name":"e4f-builds"},"object":{"key":"myapp-stag-abc1234.zip"}}}]}`
+
+	key, ok := parseS3EventKey(body, "e4f-builds", "myapp-stag-")
+	require.True(t, ok)
+	require.Equal(t, "myapp-stag-abc1234.zip", key)
+
+	_, ok = parseS3EventKey(body, "other-bucket", "myapp-stag-")
+	require.False(t, ok)
+
+	_, ok = parseS3EventKey(body, "e4f-builds", "otherapp-stag-")
+	require.False(t, ok)
+
+	_, ok = parseS3EventKey("not json", "e4f-builds", "myapp-stag-")
+	require.False(t, ok)
+}
@@ -0,0 +1,101 @@
+package pkg
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/transport"
+)
+
+// GitSource clones URL at Ref into a temporary directory on Resolve, so CI
+// can deploy a tagged release of a private repo without a pre-existing
+// working copy. A cloned checkout is never dirty.
+type GitSource struct {
+	URL  string
+	Ref  string
+	Auth transport.AuthMethod
+
+	dir string
+}
+
+func (s *GitSource) Resolve(ctx context.Context) (string, bool, error) {
+	dir, err := os.MkdirTemp("", "deployer-source-*")
+	if err != nil {
+		return "", false, fmt.Errorf("error creating checkout directory: %w", err)
+	}
+	s.dir = dir
+
+	repo, err := git.PlainCloneContext(ctx, dir, false, &git.CloneOptions{
+		URL:           s.URL,
+		Auth:          s.Auth,
+		ReferenceName: plumbing.NewTagReferenceName(s.Ref),
+		SingleBranch:  true,
+		Depth:         1,
+	})
+	if err != nil {
+		// Ref isn't a tag; clone the default branch and check it out
+		// explicitly, as a branch name or bare commit hash.
+		repo, err = git.PlainCloneContext(ctx, dir, false, &git.CloneOptions{URL: s.URL, Auth: s.Auth})
+		if err != nil {
+			return "", false, fmt.Errorf("error cloning %s: %w", s.URL, err)
+		}
+		if err := s.checkoutRef(repo); err != nil {
+			return "", false, err
+		}
+	}
+
+	head, err := repo.Head()
+	if err != nil {
+		return "", false, fmt.Errorf("error resolving HEAD of %s: %w", s.URL, err)
+	}
+
+	return head.Hash().String()[0:7], false, nil
+}
+
+func (s *GitSource) checkoutRef(repo *git.Repository) error {
+	worktree, err := repo.Worktree()
+	if err != nil {
+		return fmt.Errorf("error opening worktree: %w", err)
+	}
+
+	if err := worktree.Checkout(&git.CheckoutOptions{Branch: plumbing.NewBranchReferenceName(s.Ref)}); err == nil {
+		return nil
+	}
+
+	// A full clone only creates a local branch for the default branch;
+	// every other branch exists as an origin remote-tracking ref.
+	if ref, err := repo.Reference(plumbing.NewRemoteReferenceName("origin", s.Ref), true); err == nil {
+		if err := worktree.Checkout(&git.CheckoutOptions{Hash: ref.Hash()}); err != nil {
+			return fmt.Errorf("error checking out %q: %w", s.Ref, err)
+		}
+		return nil
+	}
+
+	hash, err := repo.ResolveRevision(plumbing.Revision(s.Ref))
+	if err != nil {
+		return fmt.Errorf("error resolving ref %q: %w", s.Ref, err)
+	}
+	if err := worktree.Checkout(&git.CheckoutOptions{Hash: *hash}); err != nil {
+		return fmt.Errorf("error checking out %q: %w", s.Ref, err)
+	}
+	return nil
+}
+
+func (s *GitSource) Dir() string {
+	return s.dir
+}
+
+// Close removes the temporary checkout directory Resolve created. Callers
+// are expected to call it once they're done building from Dir().
+func (s *GitSource) Close() error {
+	if s.dir == "" {
+		return nil
+	}
+	if err := os.RemoveAll(s.dir); err != nil {
+		return fmt.Errorf("error removing checkout directory %s: %w", s.dir, err)
+	}
+	return nil
+}
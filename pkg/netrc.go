@@ -0,0 +1,51 @@
+package pkg
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// netrcMachine holds the login/password entry found for a host in a netrc
+// file.
+type netrcMachine struct {
+	Login    string
+	Password string
+}
+
+// lookupNetrc parses a netrc file for a "machine host login ... password
+// ..." entry. It returns (nil, nil) if the file doesn't exist or has no
+// matching entry, since public repos need no auth at all.
+func lookupNetrc(path, host string) (*netrcMachine, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("error reading %s: %w", path, err)
+	}
+
+	fields := strings.Fields(string(data))
+	var machine netrcMachine
+	matched := false
+
+	for i := 0; i+1 < len(fields); i++ {
+		switch fields[i] {
+		case "machine":
+			matched = fields[i+1] == host
+		case "login":
+			if matched {
+				machine.Login = fields[i+1]
+			}
+		case "password":
+			if matched {
+				machine.Password = fields[i+1]
+			}
+		}
+	}
+
+	if machine.Login == "" && machine.Password == "" {
+		return nil, nil
+	}
+	return &machine, nil
+}
@@ -0,0 +1,51 @@
+package pkg
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// Source resolves the commit Deployer.Build packages and the directory its
+// checkout lives in. LocalSource is the deployer's original behavior,
+// building whatever is on disk in the current working directory; GitSource
+// clones a remote ref into a temporary checkout, so CI can deploy a tagged
+// release of a private repo without a pre-existing working copy.
+type Source interface {
+	// Resolve prepares the checkout (cloning first if necessary) and
+	// returns the commit to build and whether its tree has uncommitted
+	// changes.
+	Resolve(ctx context.Context) (commit string, dirty bool, err error)
+	// Dir is the directory `go build` should run in.
+	Dir() string
+	// Close releases any resources Resolve acquired, e.g. GitSource's
+	// temporary checkout directory.
+	Close() error
+}
+
+// LocalSource builds from the current working directory's git checkout.
+type LocalSource struct{}
+
+func (LocalSource) Resolve(ctx context.Context) (string, bool, error) {
+	commit, err := exec.CommandContext(ctx, "git", "rev-parse", "HEAD").Output()
+	if err != nil {
+		return "", false, fmt.Errorf("error getting current Git commit: %w", err)
+	}
+
+	status, err := exec.CommandContext(ctx, "git", "status", "--porcelain").Output()
+	if err != nil {
+		return "", false, fmt.Errorf("error checking git status: %w", err)
+	}
+
+	commitStr := strings.TrimSpace(string(commit))[0:7]
+	return commitStr, len(status) > 0, nil
+}
+
+func (LocalSource) Dir() string {
+	return "."
+}
+
+func (LocalSource) Close() error {
+	return nil
+}
@@ -0,0 +1,126 @@
+package pkg
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/go-git/go-git/v5/plumbing/transport"
+	githttp "github.com/go-git/go-git/v5/plumbing/transport/http"
+	gitssh "github.com/go-git/go-git/v5/plumbing/transport/ssh"
+)
+
+// ParseSourceSpec parses --source into a Source. An empty spec (or
+// "local") returns LocalSource. A "git+ssh://" or "git+https://" URL with
+// a "#ref" fragment returns a GitSource that clones that URL at that ref,
+// e.g. "git+ssh://git@github.com/org/repo.git#v1.2.3". SSH URLs
+// authenticate via DEPLOYER_SSH_KEY or a default ~/.ssh identity file when
+// present, falling back to the local SSH agent; HTTP(S) URLs authenticate
+// via a matching ~/.netrc entry, if any.
+func ParseSourceSpec(spec string) (Source, error) {
+	if spec == "" || spec == "local" {
+		return LocalSource{}, nil
+	}
+
+	u, err := url.Parse(spec)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing source spec %q: %w", spec, err)
+	}
+
+	ref := u.Fragment
+	if ref == "" {
+		return nil, fmt.Errorf("source spec %q is missing a #ref", spec)
+	}
+	u.Fragment = ""
+
+	switch u.Scheme {
+	case "git+ssh":
+		u.Scheme = "ssh"
+		auth, err := sshAuth(u)
+		if err != nil {
+			return nil, fmt.Errorf("error setting up SSH auth for %q: %w", spec, err)
+		}
+		return &GitSource{URL: u.String(), Ref: ref, Auth: auth}, nil
+	case "git+https", "git+http":
+		u.Scheme = strings.TrimPrefix(u.Scheme, "git+")
+		auth, err := netrcAuth(u.Host)
+		if err != nil {
+			return nil, err
+		}
+		return &GitSource{URL: u.String(), Ref: ref, Auth: auth}, nil
+	default:
+		return nil, fmt.Errorf("unsupported source scheme %q", u.Scheme)
+	}
+}
+
+func sshUser(u *url.URL) string {
+	if u.User != nil {
+		return u.User.Username()
+	}
+	return "git"
+}
+
+// sshAuth resolves SSH auth for u without eagerly requiring a running SSH
+// agent: it prefers an explicit private key (DEPLOYER_SSH_KEY, falling
+// back to the default ~/.ssh identities) and only dials the agent when
+// SSH_AUTH_SOCK is actually set. When neither is available it returns a
+// nil AuthMethod, deferring to go-git's own default resolution at clone
+// time instead of failing here — the common case on CI runners without
+// agent forwarding or a pre-placed key.
+func sshAuth(u *url.URL) (transport.AuthMethod, error) {
+	user := sshUser(u)
+
+	if keyFile := sshKeyFile(); keyFile != "" {
+		auth, err := gitssh.NewPublicKeysFromFile(user, keyFile, "")
+		if err != nil {
+			return nil, fmt.Errorf("error loading SSH key %s: %w", keyFile, err)
+		}
+		return auth, nil
+	}
+
+	if os.Getenv("SSH_AUTH_SOCK") == "" {
+		return nil, nil
+	}
+
+	return gitssh.NewSSHAgentAuth(user)
+}
+
+// sshKeyFile returns the path to an explicit SSH private key to use for
+// git+ssh sources, or "" if none is configured. DEPLOYER_SSH_KEY takes
+// precedence; otherwise the default ~/.ssh identities are tried in order.
+func sshKeyFile() string {
+	if path := os.Getenv("DEPLOYER_SSH_KEY"); path != "" {
+		return path
+	}
+
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+
+	for _, name := range []string{"id_ed25519", "id_rsa"} {
+		path := filepath.Join(home, ".ssh", name)
+		if _, err := os.Stat(path); err == nil {
+			return path
+		}
+	}
+	return ""
+}
+
+// netrcAuth looks up host in ~/.netrc and returns HTTP basic auth built
+// from its login/password, or nil if the host has no entry.
+func netrcAuth(host string) (transport.AuthMethod, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return nil, fmt.Errorf("error locating home directory for netrc lookup: %w", err)
+	}
+
+	machine, err := lookupNetrc(filepath.Join(home, ".netrc"), host)
+	if err != nil || machine == nil {
+		return nil, err
+	}
+
+	return &githttp.BasicAuth{Username: machine.Login, Password: machine.Password}, nil
+}
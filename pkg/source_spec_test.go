@@ -0,0 +1,53 @@
+package pkg
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseSourceSpec(t *testing.T) {
+	t.Run("empty defaults to local", func(t *testing.T) {
+		src, err := ParseSourceSpec("")
+		require.NoError(t, err)
+		require.Equal(t, LocalSource{}, src)
+	})
+
+	t.Run("explicit local", func(t *testing.T) {
+		src, err := ParseSourceSpec("local")
+		require.NoError(t, err)
+		require.Equal(t, LocalSource{}, src)
+	})
+
+	t.Run("git+ssh with ref", func(t *testing.T) {
+		t.Setenv("DEPLOYER_SSH_KEY", "")
+		t.Setenv("SSH_AUTH_SOCK", "")
+		t.Setenv("HOME", t.TempDir())
+
+		src, err := ParseSourceSpec("git+ssh://git@github.com/org/repo.git#v1.2.3")
+		require.NoError(t, err)
+		gitSrc, ok := src.(*GitSource)
+		require.True(t, ok)
+		require.Equal(t, "ssh://git@github.com/org/repo.git", gitSrc.URL)
+		require.Equal(t, "v1.2.3", gitSrc.Ref)
+	})
+
+	t.Run("git+https with ref", func(t *testing.T) {
+		src, err := ParseSourceSpec("git+https://github.com/org/repo.git#main")
+		require.NoError(t, err)
+		gitSrc, ok := src.(*GitSource)
+		require.True(t, ok)
+		require.Equal(t, "https://github.com/org/repo.git", gitSrc.URL)
+		require.Equal(t, "main", gitSrc.Ref)
+	})
+
+	t.Run("missing ref", func(t *testing.T) {
+		_, err := ParseSourceSpec("git+https://github.com/org/repo.git")
+		require.Error(t, err)
+	})
+
+	t.Run("unsupported scheme", func(t *testing.T) {
+		_, err := ParseSourceSpec("ftp://example.com/repo#main")
+		require.Error(t, err)
+	})
+}